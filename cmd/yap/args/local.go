@@ -0,0 +1,88 @@
+package args
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os/user"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/config"
+	"github.com/rytsh/yap/internal/guard"
+	"github.com/rytsh/yap/internal/tui"
+	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/style"
+)
+
+var localCmd = &cobra.Command{
+	Use:   "local",
+	Short: "run the screen on the current terminal",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd.Context(), cmd.Flags().Visit); err != nil {
+			return err
+		}
+
+		// query the terminal once before the program reads the input, adaptive
+		// colors of the themes are resolved with the background
+		if config.Application.Theme.Background == "" && !lipgloss.HasDarkBackground() {
+			config.Application.Theme.Background = style.BackgroundLight
+		}
+
+		if err := validateConfig(); err != nil {
+			return err
+		}
+
+		if err := audit.Setup(config.Application.Audit); err != nil {
+			return err //nolint:wrapcheck // no need
+		}
+
+		defer closeAudit()
+
+		if err := guard.Setup(config.Application.Guard); err != nil {
+			return err //nolint:wrapcheck // no need
+		}
+
+		return runLocal()
+	},
+}
+
+func runLocal() error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("session id: %w", err)
+	}
+
+	userName := ""
+	if u, err := user.Current(); err == nil {
+		userName = u.Username
+	}
+
+	session := model.NewSession(hex.EncodeToString(id), userName, "local")
+	session.Cache = config.Application.Record
+
+	defer session.Close()
+
+	session.Audit(audit.Event{Type: audit.TypeConnect})
+	defer func() {
+		session.Audit(audit.Event{Type: audit.TypeDisconnect, Duration: time.Since(session.Start).Seconds()})
+	}()
+
+	// size is sent by the program with the first window size message
+	m := config.Application.Screen.Start(session, 0, 0)
+
+	final, err := tui.NewProgram(session, m, config.Application.Timeout, tea.WithAltScreen()).Run()
+	if err != nil {
+		return fmt.Errorf("local program: %w", err)
+	}
+
+	if err := tui.Closed(final); err != nil {
+		fmt.Println(err.Error())
+	}
+
+	return nil
+}
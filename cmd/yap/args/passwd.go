@@ -0,0 +1,99 @@
+package args
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/rytsh/yap/internal/tui/view/login/auth"
+)
+
+var passwdConfig = struct {
+	File      string
+	Algorithm string
+	Cost      int
+}{
+	Algorithm: auth.HashBcrypt,
+}
+
+var passwdCmd = &cobra.Command{
+	Use:   "passwd <user>",
+	Short: "hash a password for the basic auth",
+	Long: "hash a password for the basic auth, the entry is printed or set in the htpasswd file.\n" +
+		"password is asked on the terminal or read from the first line of the input.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		password, err := readPassword(cmd)
+		if err != nil {
+			return err
+		}
+
+		hash, err := auth.HashPassword(passwdConfig.Algorithm, password, passwdConfig.Cost)
+		if err != nil {
+			return err //nolint:wrapcheck // no need
+		}
+
+		if passwdConfig.File == "" {
+			fmt.Fprintln(cmd.OutOrStdout(), args[0]+":"+hash)
+
+			return nil
+		}
+
+		if err := auth.SetHtpasswd(passwdConfig.File, args[0], hash); err != nil {
+			return err //nolint:wrapcheck // no need
+		}
+
+		fmt.Fprintf(cmd.ErrOrStderr(), "user %q is set in %s\n", args[0], passwdConfig.File)
+
+		return nil
+	},
+}
+
+// readPassword asks the password twice on a terminal.
+func readPassword(cmd *cobra.Command) (string, error) {
+	if f, ok := cmd.InOrStdin().(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(cmd.ErrOrStderr(), "Password: ")
+		password, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(cmd.ErrOrStderr())
+
+		if err != nil {
+			return "", fmt.Errorf("read password: %w", err)
+		}
+
+		fmt.Fprint(cmd.ErrOrStderr(), "Retype password: ")
+		again, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(cmd.ErrOrStderr())
+
+		if err != nil {
+			return "", fmt.Errorf("read password: %w", err)
+		}
+
+		if string(password) != string(again) {
+			return "", errors.New("passwords do not match")
+		}
+
+		if len(password) == 0 {
+			return "", errors.New("password is empty")
+		}
+
+		return string(password), nil
+	}
+
+	line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password is empty")
+	}
+
+	return password, nil
+}
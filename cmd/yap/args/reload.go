@@ -0,0 +1,141 @@
+package args
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/worldline-go/igconfig"
+	"github.com/worldline-go/igconfig/loader"
+
+	"github.com/rytsh/yap/internal/config"
+	"github.com/rytsh/yap/internal/tui"
+)
+
+// watchConfig reloads the screen on SIGHUP, config file changes and consul key changes.
+// Sessions started before the reload keep their screen.
+func watchConfig(ctx context.Context, store *tui.Store) {
+	logReload := log.With().Str("component", "reload").Logger()
+	ctx = logReload.WithContext(ctx)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+
+	defer signal.Stop(sig)
+
+	var fileChanges <-chan time.Time
+
+	file := ""
+	if config.LoadConfig.ConfigSet.File {
+		file = config.FilePath()
+	}
+
+	if file != "" && config.Application.Reload.Interval > 0 {
+		ticker := time.NewTicker(config.Application.Reload.Interval)
+		defer ticker.Stop()
+
+		fileChanges = ticker.C
+	}
+
+	lastStat := statFile(file)
+
+	var consulChanges <-chan []byte
+
+	if config.LoadConfig.ConfigSet.Consul {
+		ch, err := loader.Consul{}.DynamicValue(ctx, config.LoadConfig.AppName)
+		if err != nil {
+			logReload.Warn().Err(err).Msg("could not watch consul key")
+		}
+
+		consulChanges = ch
+	}
+
+	for {
+		reason := ""
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			reason = "SIGHUP"
+		case <-fileChanges:
+			if stat := statFile(file); stat != lastStat {
+				lastStat = stat
+				reason = "file " + file
+			}
+		case _, ok := <-consulChanges:
+			if !ok {
+				consulChanges = nil
+
+				continue
+			}
+
+			reason = "consul"
+		}
+
+		if reason == "" {
+			continue
+		}
+
+		screen, err := loadScreen(ctx)
+		if err != nil {
+			logReload.Error().Err(err).Str("trigger", reason).Msg("screen reload failed, keeping the current screen")
+
+			continue
+		}
+
+		diff := tui.Diff(store.Swap(screen), screen)
+		if diff == "" {
+			logReload.Info().Str("trigger", reason).Msg("screen reloaded, no changes")
+
+			continue
+		}
+
+		logReload.Info().Str("trigger", reason).Msgf("screen reloaded: %s", diff)
+	}
+}
+
+// loadScreen loads the application config again and returns the validated screen.
+func loadScreen(ctx context.Context) (tui.Screen, error) {
+	app := config.NewApp()
+
+	if err := igconfig.LoadWithLoadersWithContext(ctx, config.LoadConfig.AppName, &app, appLoaders()...); err != nil {
+		return nil, fmt.Errorf("unable to load configuration settings: %w", err)
+	}
+
+	if err := app.Theme.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid theme:\n%w", err)
+	}
+
+	if err := app.Screen.SetTheme(app.Theme); err != nil {
+		return nil, fmt.Errorf("invalid screen theme:\n%w", err)
+	}
+
+	if err := app.Screen.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid screen:\n%w", err)
+	}
+
+	return app.Screen, nil
+}
+
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(file string) fileStat {
+	if file == "" {
+		return fileStat{}
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return fileStat{}
+	}
+
+	return fileStat{modTime: info.ModTime(), size: info.Size()}
+}
@@ -0,0 +1,45 @@
+package args
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rytsh/yap/internal/hold"
+)
+
+// resetTerminal leaves the alternate screen and shows the cursor.
+const resetTerminal = "\x1b[?1049l\x1b[?25h"
+
+var replayConfig = hold.ReplayConfig{
+	Speed: 1,
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <file>",
+	Short: "play a session recording",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("open recording: %w", err)
+		}
+		defer f.Close()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		_, err = hold.Replay(ctx, f, os.Stdout, replayConfig)
+
+		fmt.Fprint(os.Stdout, resetTerminal)
+
+		if err != nil && ctx.Err() == nil {
+			return err //nolint:wrapcheck // no need
+		}
+
+		return nil
+	},
+}
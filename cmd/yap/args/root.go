@@ -17,8 +17,12 @@ import (
 	"github.com/worldline-go/igconfig/loader"
 	"github.com/worldline-go/logz"
 
+	"github.com/rytsh/yap/internal/audit"
 	"github.com/rytsh/yap/internal/config"
+	"github.com/rytsh/yap/internal/guard"
+	"github.com/rytsh/yap/internal/metric"
 	"github.com/rytsh/yap/internal/server"
+	"github.com/rytsh/yap/internal/tui"
 )
 
 var ErrShutdown = errors.New("shutting down signal received")
@@ -42,6 +46,20 @@ var rootCmd = &cobra.Command{
 			return err
 		}
 
+		if err := validateConfig(); err != nil {
+			return err
+		}
+
+		if err := audit.Setup(config.Application.Audit); err != nil {
+			return err //nolint:wrapcheck // no need
+		}
+
+		defer closeAudit()
+
+		if err := guard.Setup(config.Application.Guard); err != nil {
+			return err //nolint:wrapcheck // no need
+		}
+
 		if err := runRoot(cmd.Context()); err != nil && !errors.Is(err, ErrShutdown) {
 			return err
 		}
@@ -66,6 +84,18 @@ func setFlags() {
 	rootCmd.PersistentFlags().StringVarP(&config.Application.LogLevel, "log-level", "l", config.Application.LogLevel, "log level")
 	rootCmd.PersistentFlags().StringVarP(&config.Application.Server.Host, "host", "H", config.Application.Server.Host, "host")
 	rootCmd.PersistentFlags().IntVarP(&config.Application.Server.Port, "port", "P", config.Application.Server.Port, "port")
+
+	replayCmd.Flags().Float64VarP(&replayConfig.Speed, "speed", "s", replayConfig.Speed, "playback speed")
+	replayCmd.Flags().DurationVarP(&replayConfig.MaxIdle, "max-idle", "i", replayConfig.MaxIdle, "limit idle time between events")
+
+	passwdCmd.Flags().StringVarP(&passwdConfig.File, "file", "f", passwdConfig.File, "htpasswd file to add or update the user")
+	passwdCmd.Flags().StringVarP(&passwdConfig.Algorithm, "algorithm", "a", passwdConfig.Algorithm, "hash algorithm; bcrypt, sha or md5")
+	passwdCmd.Flags().IntVarP(&passwdConfig.Cost, "cost", "c", passwdConfig.Cost, "bcrypt cost, default if zero")
+
+	rootCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(localCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(passwdCmd)
 }
 
 // override function hold first values of definitions.
@@ -83,11 +113,7 @@ func loadConfig(ctx context.Context, visit func(fn func(*pflag.Flag))) error {
 	logConfig := log.With().Str("component", "config").Logger()
 	ctxConfig := logConfig.WithContext(ctx)
 
-	loaders := []loader.Loader{}
-
-	envLoader := &loader.Env{}
-
-	if err := igconfig.LoadWithLoadersWithContext(ctxConfig, "", &config.LoadConfig, envLoader); err != nil {
+	if err := igconfig.LoadWithLoadersWithContext(ctxConfig, "", &config.LoadConfig, &loader.Env{}); err != nil {
 		return fmt.Errorf("unable to load prefix settings: %v", err)
 	}
 
@@ -97,21 +123,7 @@ func loadConfig(ctx context.Context, visit func(fn func(*pflag.Flag))) error {
 	loader.VaultSecretBasePath = config.LoadConfig.Prefix.Vault
 	loader.VaultSecretAdditionalPaths = nil
 
-	if config.LoadConfig.ConfigSet.Consul {
-		loaders = append(loaders, &loader.Consul{})
-	}
-
-	if config.LoadConfig.ConfigSet.Vault && config.LoadConfig.Prefix.Vault != "" {
-		loaders = append(loaders, &loader.Vault{})
-	}
-
-	if config.LoadConfig.ConfigSet.File {
-		loaders = append(loaders, &loader.File{})
-	}
-
-	loaders = append(loaders, envLoader)
-
-	if err := igconfig.LoadWithLoadersWithContext(ctxConfig, config.LoadConfig.AppName, &config.Application, loaders...); err != nil {
+	if err := igconfig.LoadWithLoadersWithContext(ctxConfig, config.LoadConfig.AppName, &config.Application, appLoaders()...); err != nil {
 		return fmt.Errorf("unable to load configuration settings: %v", err)
 	}
 
@@ -133,6 +145,31 @@ func loadConfig(ctx context.Context, visit func(fn func(*pflag.Flag))) error {
 	return nil
 }
 
+func closeAudit() {
+	if err := audit.Close(); err != nil {
+		log.Error().Err(err).Msg("failed to close audit output")
+	}
+}
+
+// appLoaders returns the loaders of the application config with the load config settings.
+func appLoaders() []loader.Loader {
+	loaders := []loader.Loader{}
+
+	if config.LoadConfig.ConfigSet.Consul {
+		loaders = append(loaders, &loader.Consul{})
+	}
+
+	if config.LoadConfig.ConfigSet.Vault && config.LoadConfig.Prefix.Vault != "" {
+		loaders = append(loaders, &loader.Vault{})
+	}
+
+	if config.LoadConfig.ConfigSet.File {
+		loaders = append(loaders, &loader.File{})
+	}
+
+	return append(loaders, &loader.Env{})
+}
+
 func runRoot(ctxParent context.Context) (err error) {
 	wg := &sync.WaitGroup{}
 	defer wg.Wait()
@@ -163,15 +200,35 @@ func runRoot(ctxParent context.Context) (err error) {
 		shutdown.Global.Run()
 	}()
 
+	if err := metric.Serve(wg, config.Application.Metrics); err != nil {
+		return err
+	}
+
+	screen := tui.NewStore(config.Application.Screen)
+
 	// application codes
 	if err := server.Serve(wg, server.Config{
-		Host:   config.Application.Server.Host,
-		Port:   config.Application.Server.Port,
-		Screen: config.Application.Screen,
+		Host: config.Application.Server.Host,
+		Port: config.Application.Server.Port,
+
+		HostKey:   config.Application.Server.HostKey,
+		PublicKey: config.Application.Server.PublicKey,
+		Record:    config.Application.Record,
+		Timeout:   config.Application.Timeout,
+
+		Screen: screen,
 	}); err != nil {
 		return err
 	}
 
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		watchConfig(ctx, screen)
+	}()
+
 	wg.Wait()
 
 	return nil
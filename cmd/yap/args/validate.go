@@ -0,0 +1,47 @@
+package args
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/rytsh/yap/internal/config"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "validate the configuration",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd.Context(), cmd.Flags().Visit); err != nil {
+			return err
+		}
+
+		issues := config.Check()
+		for _, issue := range issues {
+			fmt.Fprintln(cmd.OutOrStdout(), issue.Error())
+		}
+
+		if len(issues) > 0 {
+			return fmt.Errorf("configuration has %d issues", len(issues))
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), "configuration is valid")
+
+		return nil
+	},
+}
+
+// validateConfig logs the configuration issues.
+func validateConfig() error {
+	issues := config.Check()
+	for _, issue := range issues {
+		log.Error().Msg(issue.Error())
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("invalid configuration, %d issues", len(issues))
+	}
+
+	return nil
+}
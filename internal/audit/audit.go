@@ -0,0 +1,289 @@
+// Package audit writes the user actions as JSON lines.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/rytsh/yap/internal/check"
+)
+
+// Event types.
+const (
+	TypeConnect    = "connect"
+	TypeDisconnect = "disconnect"
+	TypeAuth       = "auth"
+	TypeNavigate   = "navigate"
+	TypeAction     = "action"
+	TypeTimeout    = "timeout"
+)
+
+// Results of the events.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	ResultStarted = "started"
+	ResultError   = "error"
+	// ResultCanceled is a declined confirmation.
+	ResultCanceled = "canceled"
+)
+
+// QueueSize is the buffered event count of the webhook.
+var QueueSize = 1024
+
+type Config struct {
+	// Output is "stdout", a file path or an http(s) webhook URL, audit is disabled if empty.
+	Output string `cfg:"output"`
+	// Timeout of the webhook requests.
+	Timeout time.Duration `cfg:"timeout"`
+	// Headers are added to the webhook requests.
+	Headers map[string]string `cfg:"headers" loggable:"false"`
+}
+
+type Event struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	SessionID  string    `json:"session_id"`
+	RemoteAddr string    `json:"remote_addr"`
+	// User is the SSH user name.
+	User string `json:"user,omitempty"`
+	// Identity is the authenticated user.
+	Identity string `json:"identity,omitempty"`
+	Method   string `json:"method,omitempty"`
+
+	Tab    string `json:"tab,omitempty"`
+	View   string `json:"view,omitempty"`
+	Action string `json:"action,omitempty"`
+	Name   string `json:"name,omitempty"`
+
+	Params   map[string]interface{} `json:"params,omitempty"`
+	Result   string                 `json:"result,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Duration float64                `json:"duration,omitempty"`
+}
+
+type writer interface {
+	write(e Event)
+	close() error
+}
+
+var (
+	mutex   sync.RWMutex
+	current writer
+)
+
+func (c Config) Enabled() bool {
+	return c.Output != ""
+}
+
+func (c Config) webhook() bool {
+	return strings.HasPrefix(c.Output, "http://") || strings.HasPrefix(c.Output, "https://")
+}
+
+// Validate checks the output.
+func (c Config) Validate() error {
+	var issues check.Issues
+
+	if c.Timeout < 0 {
+		issues.Addf("timeout", "timeout should be positive")
+	}
+
+	if len(c.Headers) > 0 && !c.webhook() {
+		issues.Addf("headers", "headers are used only with a webhook output")
+	}
+
+	return issues.Err()
+}
+
+// Setup opens the output, previous output is closed.
+func Setup(c Config) error {
+	var w writer
+
+	switch {
+	case !c.Enabled():
+	case c.Output == "stdout":
+		w = &streamWriter{w: os.Stdout}
+	case c.webhook():
+		w = newWebhookWriter(c)
+	default:
+		f, err := os.OpenFile(c.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+		if err != nil {
+			return fmt.Errorf("open audit file: %w", err)
+		}
+
+		w = &streamWriter{w: f, closer: f}
+	}
+
+	mutex.Lock()
+	old := current
+	current = w
+	mutex.Unlock()
+
+	if old != nil {
+		return old.close()
+	}
+
+	return nil
+}
+
+// Close flushes and closes the output.
+func Close() error {
+	return Setup(Config{})
+}
+
+// Log writes the event if audit is enabled.
+func Log(e Event) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if current == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	current.write(e)
+}
+
+// ErrString returns the message of the error, empty if nil.
+func ErrString(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
+
+// Result returns success or failure with the error.
+func Result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+
+	return ResultSuccess
+}
+
+type streamWriter struct {
+	mutex  sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+func (s *streamWriter) write(e Event) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := json.NewEncoder(s.w).Encode(e); err != nil {
+		log.Error().Err(err).Msg("failed to write audit event")
+	}
+}
+
+func (s *streamWriter) close() error {
+	if s.closer == nil {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.closer.Close() //nolint:wrapcheck // no need
+}
+
+// webhookWriter posts events in the background, events are dropped if the queue is full.
+type webhookWriter struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	queue   chan Event
+	done    chan struct{}
+}
+
+func newWebhookWriter(c Config) *webhookWriter {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	w := &webhookWriter{
+		url:     c.Output,
+		headers: c.Headers,
+		client:  &http.Client{Timeout: timeout},
+		queue:   make(chan Event, QueueSize),
+		done:    make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w
+}
+
+func (w *webhookWriter) write(e Event) {
+	select {
+	case w.queue <- e:
+	default:
+		log.Warn().Str("type", e.Type).Str("session_id", e.SessionID).Msg("audit queue is full, event dropped")
+	}
+}
+
+func (w *webhookWriter) run() {
+	defer close(w.done)
+
+	for e := range w.queue {
+		if err := w.post(e); err != nil {
+			log.Error().Err(err).Str("type", e.Type).Str("session_id", e.SessionID).Msg("failed to send audit event")
+		}
+	}
+}
+
+func (w *webhookWriter) post(e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send event: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// close sends the queued events.
+func (w *webhookWriter) close() error {
+	close(w.queue)
+	<-w.done
+
+	return nil
+}
@@ -0,0 +1,122 @@
+// Package check holds configuration issues with their paths.
+package check
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Issue is a problem of the configuration at the path.
+type Issue struct {
+	// Path is the dotted config path like screen[0].selection.login.
+	Path    string
+	Message string
+
+	// Position of the path in the config file, set if known.
+	File   string
+	Line   int
+	Column int
+}
+
+func (i Issue) Error() string {
+	var b strings.Builder
+
+	if i.File != "" {
+		b.WriteString(i.File)
+		if i.Line > 0 {
+			fmt.Fprintf(&b, ":%d:%d", i.Line, i.Column)
+		}
+
+		b.WriteString(": ")
+	}
+
+	if i.Path != "" {
+		b.WriteString(i.Path)
+		b.WriteString(": ")
+	}
+
+	b.WriteString(i.Message)
+
+	return b.String()
+}
+
+// Issues is a list of issues, nil error if empty.
+type Issues []Issue
+
+func (is Issues) Error() string {
+	v := make([]string, len(is))
+	for i := range is {
+		v[i] = is[i].Error()
+	}
+
+	return strings.Join(v, "\n")
+}
+
+// Err returns nil if there is no issue.
+func (is Issues) Err() error {
+	if len(is) == 0 {
+		return nil
+	}
+
+	return is
+}
+
+// Add appends the error under the path, issues inside the error get the path as prefix.
+func (is *Issues) Add(path string, err error) {
+	if err == nil {
+		return
+	}
+
+	var sub Issues
+	if errors.As(err, &sub) {
+		for _, i := range sub {
+			i.Path = Join(path, i.Path)
+			*is = append(*is, i)
+		}
+
+		return
+	}
+
+	var issue Issue
+	if errors.As(err, &issue) {
+		issue.Path = Join(path, issue.Path)
+		*is = append(*is, issue)
+
+		return
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok { //nolint:errorlint // joined errors
+		for _, e := range joined.Unwrap() {
+			is.Add(path, e)
+		}
+
+		return
+	}
+
+	*is = append(*is, Issue{Path: path, Message: err.Error()})
+}
+
+// Addf appends a new issue.
+func (is *Issues) Addf(path, format string, args ...interface{}) {
+	*is = append(*is, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Join joins the paths, sub path can start with an index.
+func Join(path, sub string) string {
+	switch {
+	case path == "":
+		return sub
+	case sub == "":
+		return path
+	case strings.HasPrefix(sub, "["):
+		return path + sub
+	}
+
+	return path + "." + sub
+}
+
+// Index returns the path of the element.
+func Index(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
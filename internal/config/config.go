@@ -1,20 +1,66 @@
 package config
 
-import "github.com/rytsh/yap/internal/tui"
+import (
+	"time"
 
-var Application = struct {
-	LogLevel string     `cfg:"log-level"`
-	Server   Server     `cfg:"server"`
-	Screen   tui.Screen `cfg:"screen"`
-}{
-	LogLevel: "info",
-	Server: Server{
-		Host: "0.0.0.0",
-		Port: 2222,
-	},
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/guard"
+	"github.com/rytsh/yap/internal/hold"
+	"github.com/rytsh/yap/internal/metric"
+	"github.com/rytsh/yap/internal/server"
+	"github.com/rytsh/yap/internal/tui"
+	"github.com/rytsh/yap/internal/tui/style"
+)
+
+var Application = NewApp()
+
+type App struct {
+	LogLevel string        `cfg:"log-level"`
+	Server   Server        `cfg:"server"`
+	Reload   Reload        `cfg:"reload"`
+	Metrics  metric.Server `cfg:"metrics"`
+	Audit    audit.Config  `cfg:"audit"`
+	Guard    guard.Config  `cfg:"guard"`
+	Timeout  tui.Timeout   `cfg:"timeout"`
+	Record   hold.Cache    `cfg:"record"`
+	Theme    style.Themes  `cfg:"theme"`
+	Screen   tui.Screen    `cfg:"screen"`
+}
+
+// NewApp returns the application config with default values.
+func NewApp() App {
+	return App{
+		LogLevel: "info",
+		Server: Server{
+			Host: "0.0.0.0",
+			Port: 2222,
+		},
+		Reload: Reload{
+			Interval: 5 * time.Second,
+		},
+		Guard: guard.Config{
+			Delay:        time.Second,
+			MaxDelay:     30 * time.Second,
+			LockAfter:    10,
+			LockDuration: 15 * time.Minute,
+			MaxAttempts:  5,
+			Forget:       time.Hour,
+		},
+		Timeout: tui.Timeout{
+			Warning: time.Minute,
+		},
+	}
 }
 
 type Server struct {
-	Host string
-	Port int
+	Host      string
+	Port      int
+	HostKey   server.HostKey       `cfg:"host_key"`
+	PublicKey server.PublicKeyAuth `cfg:"public_key"`
+}
+
+// Reload is the hot reload settings of the screen.
+type Reload struct {
+	// Interval is the check interval of the config file, zero disables it.
+	Interval time.Duration `cfg:"interval"`
 }
@@ -0,0 +1,281 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/worldline-go/igconfig/loader"
+	"gopkg.in/yaml.v3"
+
+	"github.com/rytsh/yap/internal/check"
+	"github.com/rytsh/yap/internal/hold"
+)
+
+var (
+	reIgnoreSeparator = regexp.MustCompile(`[-_ ]`)
+	durationType      = reflect.TypeOf(time.Duration(0))
+)
+
+type position struct {
+	line   int
+	column int
+}
+
+// FilePath returns the config file path with the same lookup of the file loader,
+// empty if not found.
+func FilePath() string {
+	if v := os.Getenv(loader.EnvConfigFile); v != "" {
+		return v
+	}
+
+	for _, dir := range []string{"", "/etc"} {
+		for _, suffix := range loader.ConfFileSuffixes {
+			path := filepath.Join(dir, LoadConfig.AppName+suffix)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+
+	return ""
+}
+
+// Validate checks the application config and prepares the providers.
+func (a *App) Validate() error {
+	var issues check.Issues
+
+	issues.Add("server.host_key", a.Server.HostKey.Prepare())
+	issues.Add("server.public_key", a.Server.PublicKey.Prepare())
+
+	if a.Reload.Interval < 0 {
+		issues.Addf("reload.interval", "interval should be positive")
+	}
+
+	issues.Add("audit", a.Audit.Validate())
+	issues.Add("guard", a.Guard.Validate())
+	issues.Add("timeout", a.Timeout.Validate())
+
+	if a.Metrics.Path != "" && !strings.HasPrefix(a.Metrics.Path, "/") {
+		issues.Addf("metrics.path", "path should start with /")
+	}
+
+	if a.Record.Enabled() {
+		_, err := a.Record.FileName(hold.RecordInfo{Time: time.Now()})
+		issues.Add("record.name", err)
+	}
+
+	issues.Add("theme", a.Theme.Validate())
+	issues.Add("screen", a.Screen.SetTheme(a.Theme))
+	issues.Add("screen", a.Screen.Validate())
+
+	return issues.Err()
+}
+
+// Check validates the loaded config and the config file keys, issues have
+// positions in the file if it is a YAML or JSON file.
+func Check() check.Issues {
+	var issues check.Issues
+
+	w := walker{pos: make(map[string]position)}
+
+	file := ""
+	if LoadConfig.ConfigSet.File {
+		file = FilePath()
+	}
+
+	if file != "" {
+		switch filepath.Ext(file) {
+		case ".yml", ".yaml", ".json":
+			w.file = file
+			if err := w.walkFile(file, reflect.TypeOf(Application)); err != nil {
+				issues.Add("", err)
+			}
+		}
+	}
+
+	issues = append(issues, w.issues...)
+	issues.Add("", Application.Validate())
+
+	// set positions from the nearest known path
+	for i := range issues {
+		if issues[i].Line != 0 || w.file == "" {
+			continue
+		}
+
+		issues[i].File = w.file
+
+		for path := issues[i].Path; ; path = parentPath(path) {
+			if p, ok := w.pos[path]; ok {
+				issues[i].Line = p.line
+				issues[i].Column = p.column
+
+				break
+			}
+
+			if path == "" {
+				break
+			}
+		}
+	}
+
+	return issues
+}
+
+func parentPath(path string) string {
+	i := strings.LastIndexAny(path, ".[")
+	if i < 0 {
+		return ""
+	}
+
+	return path[:i]
+}
+
+// walker checks yaml nodes with the config types.
+type walker struct {
+	file   string
+	pos    map[string]position
+	issues check.Issues
+}
+
+func (w *walker) walkFile(file string, t reflect.Type) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return fmt.Errorf("parse config file: %w", err)
+	}
+
+	if len(node.Content) == 0 {
+		return nil
+	}
+
+	w.walk("", node.Content[0], t)
+
+	return nil
+}
+
+func (w *walker) add(path string, node *yaml.Node, format string, args ...interface{}) {
+	w.issues = append(w.issues, check.Issue{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+		File:    w.file,
+		Line:    node.Line,
+		Column:  node.Column,
+	})
+}
+
+func (w *walker) walk(path string, node *yaml.Node, t reflect.Type) {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	if _, ok := w.pos[path]; !ok {
+		w.pos[path] = position{line: node.Line, column: node.Column}
+	}
+
+	if node.Tag == "!!null" {
+		return
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == durationType {
+		if node.Kind != yaml.ScalarNode {
+			w.add(path, node, "expected a duration")
+			return
+		}
+
+		if _, err := time.ParseDuration(node.Value); err != nil && node.Tag != "!!int" {
+			w.add(path, node, "invalid duration %q", node.Value)
+		}
+
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			w.add(path, node, "expected a mapping")
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+
+			f, ok := findField(t, k.Value)
+			if !ok {
+				w.add(check.Join(path, k.Value), k, "unknown key %q", k.Value)
+				continue
+			}
+
+			sub := check.Join(path, fieldName(f))
+			w.pos[sub] = position{line: k.Line, column: k.Column}
+			w.walk(sub, v, f.Type)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			w.add(path, node, "expected a mapping")
+			return
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+
+			sub := check.Join(path, k.Value)
+			w.pos[sub] = position{line: k.Line, column: k.Column}
+			w.walk(sub, v, t.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			w.add(path, node, "expected a list")
+			return
+		}
+
+		for i, v := range node.Content {
+			w.walk(check.Index(path, i), v, t.Elem())
+		}
+	case reflect.Interface:
+	default:
+		if node.Kind != yaml.ScalarNode {
+			w.add(path, node, "expected a %s value", t.Kind())
+		}
+	}
+}
+
+func fieldName(f reflect.StructField) string {
+	name := strings.SplitN(f.Tag.Get("cfg"), ",", 2)[0]
+	if name == "" {
+		return f.Name
+	}
+
+	return name
+}
+
+// findField matches the key as the config decoder does.
+func findField(t reflect.Type, key string) (reflect.StructField, bool) {
+	normalized := reIgnoreSeparator.ReplaceAllString(key, "")
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("cfg") == "-" {
+			continue
+		}
+
+		name := fieldName(f)
+		if name == key || strings.EqualFold(reIgnoreSeparator.ReplaceAllString(name, ""), normalized) {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rytsh/yap/internal/check"
+)
+
+type walkerConfig struct {
+	Name    string            `cfg:"name"`
+	Timeout time.Duration     `cfg:"timeout"`
+	Nested  *walkerNested     `cfg:"nested"`
+	List    []walkerNested    `cfg:"list"`
+	Labels  map[string]string `cfg:"labels"`
+	Hidden  string            `cfg:"-"`
+}
+
+type walkerNested struct {
+	ID    string `cfg:"id"`
+	Count int    `cfg:"count"`
+}
+
+func TestWalkerIssues(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []check.Issue
+	}{
+		{
+			name: "valid",
+			content: `name: yap
+timeout: 5s
+nested:
+  id: a
+list:
+  - id: b
+    count: 2
+labels:
+  env: prod
+`,
+		},
+		{
+			name: "unknown keys",
+			content: `name: yap
+nmae: typo
+nested:
+  id: a
+  colour: red
+list:
+  - id: b
+  - idd: c
+`,
+			want: []check.Issue{
+				{Path: "nmae", Message: `unknown key "nmae"`, Line: 2, Column: 1},
+				{Path: "nested.colour", Message: `unknown key "colour"`, Line: 5, Column: 3},
+				{Path: "list[1].idd", Message: `unknown key "idd"`, Line: 8, Column: 5},
+			},
+		},
+		{
+			name: "wrong kinds",
+			content: `name: [a, b]
+timeout: soon
+nested: text
+list:
+  id: a
+labels: []
+`,
+			want: []check.Issue{
+				{Path: "name", Message: "expected a string value", Line: 1, Column: 7},
+				{Path: "timeout", Message: `invalid duration "soon"`, Line: 2, Column: 10},
+				{Path: "nested", Message: "expected a mapping", Line: 3, Column: 9},
+				{Path: "list", Message: "expected a list", Line: 5, Column: 3},
+				{Path: "labels", Message: "expected a mapping", Line: 6, Column: 9},
+			},
+		},
+		{
+			name: "separators and case of the keys",
+			content: `Name: yap
+time_out: 1m
+hidden: x
+`,
+			want: []check.Issue{
+				{Path: "hidden", Message: `unknown key "hidden"`, Line: 3, Column: 1},
+			},
+		},
+		{
+			name:    "integer duration",
+			content: "timeout: 1000\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "yap.yml")
+			if err := os.WriteFile(file, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			w := walker{file: file, pos: make(map[string]position)}
+			if err := w.walkFile(file, reflect.TypeOf(walkerConfig{})); err != nil {
+				t.Fatalf("walkFile() error = %v", err)
+			}
+
+			want := make(check.Issues, 0, len(tt.want))
+			for _, i := range tt.want {
+				i.File = file
+				want = append(want, i)
+			}
+
+			got := w.issues
+			if got == nil {
+				got = check.Issues{}
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("issues =\n%v\nwant\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestWalkerPositions(t *testing.T) {
+	content := `name: yap
+nested:
+  id: a
+list:
+  - id: b
+    count: 2
+  - id: c
+labels:
+  env: prod
+`
+
+	file := filepath.Join(t.TempDir(), "yap.yml")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	w := walker{file: file, pos: make(map[string]position)}
+	if err := w.walkFile(file, reflect.TypeOf(walkerConfig{})); err != nil {
+		t.Fatalf("walkFile() error = %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want position
+	}{
+		{path: "", want: position{line: 1, column: 1}},
+		{path: "name", want: position{line: 1, column: 1}},
+		{path: "nested", want: position{line: 2, column: 1}},
+		{path: "nested.id", want: position{line: 3, column: 3}},
+		{path: "list[0]", want: position{line: 5, column: 5}},
+		{path: "list[0].count", want: position{line: 6, column: 5}},
+		{path: "list[1].id", want: position{line: 7, column: 5}},
+		{path: "labels.env", want: position{line: 9, column: 3}},
+	}
+
+	for _, tt := range tests {
+		got, ok := w.pos[tt.path]
+		if !ok {
+			t.Errorf("position of %q is missing", tt.path)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("position of %q = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParentPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "screen[0].selection.login", want: "screen[0].selection"},
+		{path: "screen[0].selection", want: "screen[0]"},
+		{path: "screen[0]", want: "screen"},
+		{path: "screen", want: ""},
+		{path: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := parentPath(tt.path); got != tt.want {
+			t.Errorf("parentPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
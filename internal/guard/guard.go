@@ -0,0 +1,301 @@
+// Package guard limits failed logins by remote address and username.
+package guard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/rytsh/yap/internal/check"
+)
+
+type Config struct {
+	// Delay is the wait after the first failure, doubled on every failure. Zero disables it.
+	Delay    time.Duration `cfg:"delay"`
+	MaxDelay time.Duration `cfg:"max_delay"`
+	// LockAfter failures of an address or a username locks it for LockDuration. Zero disables it.
+	LockAfter    int           `cfg:"lock_after"`
+	LockDuration time.Duration `cfg:"lock_duration"`
+	// MaxAttempts is the failure count of a session before it is closed. Zero is unlimited.
+	MaxAttempts int `cfg:"max_attempts"`
+	// Forget drops the failures after this duration without a new failure.
+	Forget time.Duration `cfg:"forget"`
+	// File keeps the failures across restarts, failures are in memory if empty.
+	File string `cfg:"file"`
+}
+
+// Block is a login block of an address or a username.
+type Block struct {
+	Until time.Time
+	// Locked is true for a lockout, false for a back-off delay.
+	Locked bool
+}
+
+// Remaining returns the duration of the block from now, zero if it is over.
+func (b Block) Remaining(now time.Time) time.Duration {
+	if d := b.Until.Sub(now); d > 0 {
+		return d
+	}
+
+	return 0
+}
+
+// maxBackOff limits the delay if the max delay is not set.
+const maxBackOff = 24 * time.Hour
+
+type entry struct {
+	Failures int       `json:"failures"`
+	Last     time.Time `json:"last"`
+	Until    time.Time `json:"until"`
+	Locked   bool      `json:"locked,omitempty"`
+}
+
+type store struct {
+	cfg     Config
+	entries map[string]*entry
+}
+
+var (
+	mutex   sync.Mutex
+	current = &store{entries: make(map[string]*entry)}
+)
+
+// Validate checks the durations and the limits.
+func (c Config) Validate() error {
+	var issues check.Issues
+
+	if c.Delay < 0 {
+		issues.Addf("delay", "delay should be positive")
+	}
+
+	if c.MaxDelay < 0 {
+		issues.Addf("max_delay", "max delay should be positive")
+	} else if c.MaxDelay != 0 && c.MaxDelay < c.Delay {
+		issues.Addf("max_delay", "max delay should be greater than the delay")
+	}
+
+	if c.LockAfter < 0 {
+		issues.Addf("lock_after", "lock after should be positive")
+	}
+
+	if c.LockDuration < 0 {
+		issues.Addf("lock_duration", "lock duration should be positive")
+	} else if c.LockAfter > 0 && c.LockDuration == 0 {
+		issues.Addf("lock_duration", "lock duration is required with lock after")
+	}
+
+	if c.MaxAttempts < 0 {
+		issues.Addf("max_attempts", "max attempts should be positive")
+	}
+
+	if c.Forget < 0 {
+		issues.Addf("forget", "forget should be positive")
+	}
+
+	return issues.Err()
+}
+
+// Setup sets the limits and loads the failures from the file.
+func Setup(c Config) error {
+	s := &store{cfg: c, entries: make(map[string]*entry)}
+
+	if c.File != "" {
+		content, err := os.ReadFile(c.File)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return fmt.Errorf("read guard file: %w", err)
+		case len(content) > 0:
+			if err := json.Unmarshal(content, &s.entries); err != nil {
+				return fmt.Errorf("parse guard file %s: %w", c.File, err)
+			}
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	current = s
+	current.prune(time.Now())
+
+	return nil
+}
+
+// MaxAttempts returns the failure count of a session before it is closed.
+func MaxAttempts() int {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return current.cfg.MaxAttempts
+}
+
+// Check returns the longest active block of the address and the username.
+func Check(addr, username string) Block {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	now := time.Now()
+
+	var block Block
+
+	for _, k := range keys(addr, username) {
+		e, ok := current.entries[k]
+		if !ok || !e.Until.After(now) {
+			continue
+		}
+
+		if e.Until.After(block.Until) {
+			block = Block{Until: e.Until, Locked: e.Locked}
+		}
+	}
+
+	return block
+}
+
+// Fail records a failed login and returns the block after it.
+func Fail(addr, username string) Block {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	now := time.Now()
+	cfg := current.cfg
+
+	var block Block
+
+	for _, k := range keys(addr, username) {
+		e, ok := current.entries[k]
+		if !ok {
+			e = &entry{}
+			current.entries[k] = e
+		}
+
+		e.Failures++
+		e.Last = now
+		e.Locked = false
+
+		switch {
+		case cfg.LockAfter > 0 && e.Failures >= cfg.LockAfter:
+			// lockout starts the back-off again
+			e.Failures = 0
+			e.Locked = true
+			e.Until = now.Add(cfg.LockDuration)
+		case cfg.Delay > 0:
+			e.Until = now.Add(backOff(cfg, e.Failures))
+		}
+
+		if e.Until.After(block.Until) {
+			block = Block{Until: e.Until, Locked: e.Locked}
+		}
+	}
+
+	current.save(now)
+
+	return block
+}
+
+// Success clears the failures of the username, failures of the address are
+// kept until they are forgotten so a valid account does not unlock it.
+func Success(username string) {
+	if username == "" {
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	k := "user:" + username
+	if _, ok := current.entries[k]; !ok {
+		return
+	}
+
+	delete(current.entries, k)
+	current.save(time.Now())
+}
+
+// backOff returns the delay doubled for every failure after the first one.
+func backOff(cfg Config, failures int) time.Duration {
+	limit := cfg.MaxDelay
+	if limit == 0 {
+		limit = maxBackOff
+	}
+
+	d := cfg.Delay
+	for i := 1; i < failures && d < limit; i++ {
+		d *= 2
+	}
+
+	if d > limit {
+		return limit
+	}
+
+	return d
+}
+
+func keys(addr, username string) []string {
+	v := make([]string, 0, 2)
+
+	if host := addrHost(addr); host != "" {
+		v = append(v, "addr:"+host)
+	}
+
+	if username != "" {
+		v = append(v, "user:"+username)
+	}
+
+	return v
+}
+
+// addrHost returns the address without the port.
+func addrHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
+
+// prune drops the finished blocks older than the forget duration.
+func (s *store) prune(now time.Time) {
+	if s.cfg.Forget == 0 {
+		return
+	}
+
+	for k, e := range s.entries {
+		if !e.Until.After(now) && now.Sub(e.Last) > s.cfg.Forget {
+			delete(s.entries, k)
+		}
+	}
+}
+
+// save writes the entries to the file if it is set.
+func (s *store) save(now time.Time) {
+	s.prune(now)
+
+	if s.cfg.File == "" {
+		return
+	}
+
+	content, err := json.Marshal(s.entries)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to marshal guard entries")
+		return
+	}
+
+	// replace the file at once, a crash should not leave a partial file
+	tmp := filepath.Join(filepath.Dir(s.cfg.File), "."+filepath.Base(s.cfg.File)+".tmp")
+	if err := os.WriteFile(tmp, content, 0o600); err != nil {
+		log.Error().Err(err).Msg("failed to write guard file")
+		return
+	}
+
+	if err := os.Rename(tmp, s.cfg.File); err != nil {
+		log.Error().Err(err).Msg("failed to replace guard file")
+	}
+}
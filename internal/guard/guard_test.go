@@ -0,0 +1,191 @@
+package guard
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackOff(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		failures int
+		want     time.Duration
+	}{
+		{name: "first failure", cfg: Config{Delay: time.Second}, failures: 1, want: time.Second},
+		{name: "doubled", cfg: Config{Delay: time.Second}, failures: 2, want: 2 * time.Second},
+		{name: "doubled twice", cfg: Config{Delay: time.Second}, failures: 3, want: 4 * time.Second},
+		{name: "max delay", cfg: Config{Delay: time.Second, MaxDelay: 5 * time.Second}, failures: 4, want: 5 * time.Second},
+		{name: "max delay equals delay", cfg: Config{Delay: time.Second, MaxDelay: time.Second}, failures: 10, want: time.Second},
+		{name: "no overflow without max delay", cfg: Config{Delay: time.Second}, failures: 1000, want: maxBackOff},
+		{name: "zero failures", cfg: Config{Delay: time.Second}, failures: 0, want: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := backOff(tt.cfg, tt.failures); got != tt.want {
+				t.Errorf("backOff() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFail(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		// fails are the failures before the check
+		fails       int
+		wantDelay   time.Duration
+		wantLocked  bool
+		wantBlocked bool
+	}{
+		{
+			name:  "disabled",
+			cfg:   Config{},
+			fails: 5,
+		},
+		{
+			name:        "back-off",
+			cfg:         Config{Delay: time.Minute},
+			fails:       3,
+			wantDelay:   4 * time.Minute,
+			wantBlocked: true,
+		},
+		{
+			name:        "lockout",
+			cfg:         Config{Delay: time.Minute, LockAfter: 3, LockDuration: time.Hour},
+			fails:       3,
+			wantDelay:   time.Hour,
+			wantLocked:  true,
+			wantBlocked: true,
+		},
+		{
+			name:        "back-off starts again after lockout",
+			cfg:         Config{Delay: time.Minute, LockAfter: 3, LockDuration: time.Hour},
+			fails:       4,
+			wantDelay:   time.Minute,
+			wantBlocked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Setup(tt.cfg); err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+
+			start := time.Now()
+
+			var block Block
+			for i := 0; i < tt.fails; i++ {
+				block = Fail("10.0.0.1:2222", "alice")
+			}
+
+			if block.Locked != tt.wantLocked {
+				t.Errorf("Fail() locked = %v, want %v", block.Locked, tt.wantLocked)
+			}
+
+			if got := block.Until.Sub(start); tt.wantBlocked && (got < tt.wantDelay || got > tt.wantDelay+time.Second) {
+				t.Errorf("Fail() delay = %s, want %s", got, tt.wantDelay)
+			}
+
+			// same address with another port and another user
+			check := Check("10.0.0.1:3333", "bob")
+			if blocked := check.Remaining(time.Now()) > 0; blocked != tt.wantBlocked {
+				t.Errorf("Check() address blocked = %v, want %v", blocked, tt.wantBlocked)
+			}
+
+			check = Check("10.0.0.2:2222", "alice")
+			if blocked := check.Remaining(time.Now()) > 0; blocked != tt.wantBlocked {
+				t.Errorf("Check() username blocked = %v, want %v", blocked, tt.wantBlocked)
+			}
+
+			Success("alice")
+
+			if check := Check("10.0.0.2:2222", "alice"); check.Remaining(time.Now()) > 0 {
+				t.Errorf("Check() username after success = %+v, want no block", check)
+			}
+
+			// address is not cleared by the success
+			check = Check("10.0.0.1:3333", "bob")
+			if blocked := check.Remaining(time.Now()) > 0; blocked != tt.wantBlocked {
+				t.Errorf("Check() address after success blocked = %v, want %v", blocked, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestSuccessKeepsLockedAddress(t *testing.T) {
+	if err := Setup(Config{Delay: time.Minute, LockAfter: 3, LockDuration: time.Hour}); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+
+	// guessing from one address locks it
+	for _, username := range []string{"bob", "carol", "dave"} {
+		Fail("10.0.0.1:2222", username)
+	}
+
+	if check := Check("10.0.0.1:2222", "alice"); !check.Locked {
+		t.Fatalf("Check() = %+v, want a locked address", check)
+	}
+
+	// a valid login of the address does not unlock it for the guesses
+	Success("alice")
+
+	check := Check("10.0.0.1:4444", "erin")
+	if !check.Locked || check.Remaining(time.Now()) <= 0 {
+		t.Errorf("Check() after success = %+v, want the address locked", check)
+	}
+}
+
+func TestFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "guard.json")
+	cfg := Config{Delay: time.Minute, File: file}
+
+	if err := Setup(cfg); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+
+	Fail("10.0.0.1:2222", "alice")
+
+	// failures are loaded again after a restart
+	if err := Setup(Config{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Setup(cfg); err != nil {
+		t.Fatalf("Setup() again error = %v", err)
+	}
+
+	if block := Check("10.0.0.1:1", ""); block.Remaining(time.Now()) == 0 {
+		t.Error("Check() after restart, want a block")
+	}
+
+	if block := Fail("", "alice"); block.Until.Sub(time.Now()) < 2*time.Minute-time.Second {
+		t.Errorf("Fail() after restart = %+v, want the second failure delay", block)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "empty", cfg: Config{}},
+		{name: "valid", cfg: Config{Delay: time.Second, MaxDelay: time.Minute, LockAfter: 5, LockDuration: time.Hour}},
+		{name: "max delay less than delay", cfg: Config{Delay: time.Minute, MaxDelay: time.Second}, wantErr: true},
+		{name: "lock without duration", cfg: Config{LockAfter: 3}, wantErr: true},
+		{name: "negative delay", cfg: Config{Delay: -time.Second}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
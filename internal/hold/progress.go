@@ -0,0 +1,160 @@
+package hold
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ProgressDir is the directory of the runbook progress files in the path.
+const ProgressDir = "progress"
+
+// Progress is the state of a runbook of a user.
+type Progress struct {
+	// Blocks are the results of the runnable blocks by their keys.
+	Blocks  map[string]BlockResult `json:"blocks"`
+	Updated time.Time              `json:"updated"`
+}
+
+// BlockResult is the last run of a block.
+type BlockResult struct {
+	Code  int    `json:"code"`
+	Error string `json:"error,omitempty"`
+	// Output is the end of the output.
+	Output   string    `json:"output,omitempty"`
+	Time     time.Time `json:"time"`
+	Duration float64   `json:"duration"`
+}
+
+// Success returns true if the block is finished without an error.
+func (r BlockResult) Success() bool {
+	return r.Code == 0 && r.Error == ""
+}
+
+// progressMutex serializes the saves, sessions of a user share the files.
+var progressMutex sync.Mutex
+
+// ProgressFile returns the path of the progress file of the runbook.
+func (c Cache) ProgressFile(user, runbook string) string {
+	return filepath.Join(c.Path, ProgressDir, safe(user), safe(runbook)+".json")
+}
+
+// LoadProgress reads the progress of the user, it is empty if not saved yet.
+func (c Cache) LoadProgress(user, runbook string) (Progress, error) {
+	p := Progress{Blocks: make(map[string]BlockResult)}
+	if !c.Enabled() {
+		return p, nil
+	}
+
+	content, err := os.ReadFile(c.ProgressFile(user, runbook))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return p, nil
+		}
+
+		return p, fmt.Errorf("read progress file: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &p); err != nil {
+		return p, fmt.Errorf("parse progress file: %w", err)
+	}
+
+	if p.Blocks == nil {
+		p.Blocks = make(map[string]BlockResult)
+	}
+
+	return p, nil
+}
+
+// SaveProgress merges the progress with the saved one and writes it, the newer
+// result of a block is kept. It returns the merged progress.
+func (c Cache) SaveProgress(user, runbook string, p Progress) (Progress, error) {
+	if !c.Enabled() {
+		return p, nil
+	}
+
+	progressMutex.Lock()
+	defer progressMutex.Unlock()
+
+	saved, err := c.LoadProgress(user, runbook)
+	if err != nil {
+		return p, err
+	}
+
+	p = saved.merge(p)
+
+	fileName := c.ProgressFile(user, runbook)
+	if err := os.MkdirAll(filepath.Dir(fileName), 0o750); err != nil {
+		return p, fmt.Errorf("create progress directory: %w", err)
+	}
+
+	content, err := json.Marshal(p)
+	if err != nil {
+		return p, fmt.Errorf("marshal progress: %w", err)
+	}
+
+	if err := writeFile(fileName, content, 0o640); err != nil {
+		return p, fmt.Errorf("write progress file: %w", err)
+	}
+
+	return p, nil
+}
+
+// merge returns the blocks of both, the newer result of a block is kept.
+func (p Progress) merge(other Progress) Progress {
+	v := Progress{
+		Blocks:  make(map[string]BlockResult, len(p.Blocks)+len(other.Blocks)),
+		Updated: p.Updated,
+	}
+
+	for key, r := range p.Blocks {
+		v.Blocks[key] = r
+	}
+
+	for key, r := range other.Blocks {
+		if old, ok := v.Blocks[key]; !ok || !old.Time.After(r.Time) {
+			v.Blocks[key] = r
+		}
+	}
+
+	if other.Updated.After(v.Updated) {
+		v.Updated = other.Updated
+	}
+
+	return v
+}
+
+// writeFile replaces the file with a temporary file in the same directory.
+func writeFile(fileName string, content []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	tmp := f.Name()
+
+	_, err = f.Write(content)
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+
+	if err == nil {
+		err = os.Chmod(tmp, perm)
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, fileName)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp)
+
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package hold
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgressMerge(t *testing.T) {
+	now := time.Now()
+	older := now.Add(-time.Minute)
+
+	tests := []struct {
+		name  string
+		saved map[string]BlockResult
+		p     map[string]BlockResult
+		want  map[string]BlockResult
+	}{
+		{
+			name: "other blocks are kept",
+			saved: map[string]BlockResult{
+				"check": {Code: 0, Time: older},
+			},
+			p: map[string]BlockResult{
+				"restart": {Code: 1, Time: now},
+			},
+			want: map[string]BlockResult{
+				"check":   {Code: 0, Time: older},
+				"restart": {Code: 1, Time: now},
+			},
+		},
+		{
+			name:  "newer result of the session",
+			saved: map[string]BlockResult{"check": {Code: 1, Time: older}},
+			p:     map[string]BlockResult{"check": {Code: 0, Time: now}},
+			want:  map[string]BlockResult{"check": {Code: 0, Time: now}},
+		},
+		{
+			name:  "newer result of another session",
+			saved: map[string]BlockResult{"check": {Code: 2, Time: now}},
+			p:     map[string]BlockResult{"check": {Code: 0, Time: older}},
+			want:  map[string]BlockResult{"check": {Code: 2, Time: now}},
+		},
+		{
+			name: "nothing saved",
+			p:    map[string]BlockResult{"check": {Code: 0, Time: now}},
+			want: map[string]BlockResult{"check": {Code: 0, Time: now}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Progress{Blocks: tt.saved, Updated: older}.merge(Progress{Blocks: tt.p, Updated: now})
+
+			if !reflect.DeepEqual(got.Blocks, tt.want) {
+				t.Errorf("merge() = %v, want %v", got.Blocks, tt.want)
+			}
+
+			if !got.Updated.Equal(now) {
+				t.Errorf("merge() updated = %s, want %s", got.Updated, now)
+			}
+		})
+	}
+}
+
+func TestCacheSaveProgress(t *testing.T) {
+	s := Cache{Path: t.TempDir()}
+	now := time.Now().UTC().Round(0)
+
+	// sessions loaded the same empty progress
+	first, err := s.LoadProgress("alice", "runbook")
+	if err != nil {
+		t.Fatalf("LoadProgress() error = %v", err)
+	}
+
+	second, _ := s.LoadProgress("alice", "runbook")
+
+	first.Blocks["check"] = BlockResult{Code: 0, Time: now}
+	if _, err := s.SaveProgress("alice", "runbook", first); err != nil {
+		t.Fatalf("SaveProgress() error = %v", err)
+	}
+
+	second.Blocks["restart"] = BlockResult{Code: 1, Time: now.Add(time.Second)}
+
+	merged, err := s.SaveProgress("alice", "runbook", second)
+	if err != nil {
+		t.Fatalf("SaveProgress() error = %v", err)
+	}
+
+	want := map[string]BlockResult{
+		"check":   {Code: 0, Time: now},
+		"restart": {Code: 1, Time: now.Add(time.Second)},
+	}
+
+	if !reflect.DeepEqual(merged.Blocks, want) {
+		t.Errorf("SaveProgress() = %v, want %v", merged.Blocks, want)
+	}
+
+	loaded, err := s.LoadProgress("alice", "runbook")
+	if err != nil {
+		t.Fatalf("LoadProgress() error = %v", err)
+	}
+
+	if len(loaded.Blocks) != 2 || !loaded.Blocks["restart"].Time.Equal(want["restart"].Time) {
+		t.Errorf("LoadProgress() = %v, want %v", loaded.Blocks, want)
+	}
+
+	// progress of the other users and runbooks is separate
+	if other, _ := s.LoadProgress("bob", "runbook"); len(other.Blocks) != 0 {
+		t.Errorf("LoadProgress() of another user = %v", other.Blocks)
+	}
+
+	if other, _ := s.LoadProgress("alice", "other"); len(other.Blocks) != 0 {
+		t.Errorf("LoadProgress() of another runbook = %v", other.Blocks)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(s.ProgressFile("alice", "runbook")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].Name() != "runbook.json" {
+		t.Errorf("files = %v, want only the progress file", entries)
+	}
+}
+
+func TestCacheConcurrentSaveProgress(t *testing.T) {
+	s := Cache{Path: t.TempDir()}
+	keys := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	var wg sync.WaitGroup
+
+	for _, key := range keys {
+		wg.Add(1)
+
+		go func(key string) {
+			defer wg.Done()
+
+			p := Progress{Blocks: map[string]BlockResult{key: {Time: time.Now()}}}
+			if _, err := s.SaveProgress("alice", "runbook", p); err != nil {
+				t.Errorf("SaveProgress() error = %v", err)
+			}
+		}(key)
+	}
+
+	wg.Wait()
+
+	p, err := s.LoadProgress("alice", "runbook")
+	if err != nil {
+		t.Fatalf("LoadProgress() error = %v", err)
+	}
+
+	if len(p.Blocks) != len(keys) {
+		t.Errorf("LoadProgress() = %d blocks, want %d", len(p.Blocks), len(keys))
+	}
+}
+
+func TestCacheProgressDisabled(t *testing.T) {
+	var s Cache
+
+	p := Progress{Blocks: map[string]BlockResult{"check": {}}}
+
+	got, err := s.SaveProgress("alice", "runbook", p)
+	if err != nil || !reflect.DeepEqual(got, p) {
+		t.Errorf("SaveProgress() = %v, %v, want the progress", got, err)
+	}
+
+	if loaded, err := s.LoadProgress("alice", "runbook"); err != nil || len(loaded.Blocks) != 0 {
+		t.Errorf("LoadProgress() = %v, %v, want empty", loaded, err)
+	}
+}
+
+func TestCacheProgressFile(t *testing.T) {
+	s := Cache{Path: "recordings"}
+
+	tests := []struct {
+		user    string
+		runbook string
+		want    string
+	}{
+		{user: "alice", runbook: "runbook", want: "recordings/progress/alice/runbook.json"},
+		{user: "alice@example.com", runbook: "deploy steps", want: "recordings/progress/alice@example.com/deploy_steps.json"},
+		{user: "../bob", runbook: "../../etc", want: "recordings/progress/.._bob/.._.._etc.json"},
+		{user: "..", runbook: "", want: "recordings/progress/_/_.json"},
+	}
+
+	for _, tt := range tests {
+		if got := filepath.ToSlash(s.ProgressFile(tt.user, tt.runbook)); got != tt.want {
+			t.Errorf("ProgressFile(%q, %q) = %s, want %s", tt.user, tt.runbook, got, tt.want)
+		}
+	}
+}
+
+func TestCacheCleanupKeepsProgress(t *testing.T) {
+	c := Cache{Path: t.TempDir(), MaxAge: time.Nanosecond, MaxFiles: 1}
+
+	p := Progress{Blocks: map[string]BlockResult{"check": {Time: time.Now().UTC().Round(0)}}}
+	if _, err := c.SaveProgress("alice", "runbook", p); err != nil {
+		t.Fatalf("SaveProgress() error = %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	if err := c.Cleanup(); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+
+	if _, err := os.Stat(c.ProgressFile("alice", "runbook")); err != nil {
+		t.Errorf("progress file after Cleanup() error = %v", err)
+	}
+}
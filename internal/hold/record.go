@@ -1,9 +1,303 @@
 package hold
 
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultName is the default template of the recording file name.
+const DefaultName = `{{.User}}/{{.Time.Format "20060102-150405"}}-{{.SessionID}}.cast`
+
+// Ext is the extension of the recording files.
+const Ext = ".cast"
+
+var unsafeChars = regexp.MustCompile(`[^a-zA-Z0-9._@-]+`)
+
 type Cache struct {
+	// Path is the directory of the recordings and the runbook progress,
+	// both are disabled if empty.
 	Path string `cfg:"path"`
+	// Name is a template of the file name relative to Path.
+	Name string `cfg:"name"`
+	// MaxAge removes recordings older than it.
+	MaxAge time.Duration `cfg:"max_age"`
+	// MaxFiles keeps only the newest recordings.
+	MaxFiles int `cfg:"max_files"`
+}
+
+// RecordInfo is the data of the file name template.
+type RecordInfo struct {
+	User       string
+	SessionID  string
+	RemoteAddr string
+	Time       time.Time
+
+	Width  int
+	Height int
+	Term   string
+}
+
+func (c Cache) Enabled() bool {
+	return c.Path != ""
+}
+
+// FileName returns the path of the recording file.
+func (c Cache) FileName(info RecordInfo) (string, error) {
+	name := c.Name
+	if name == "" {
+		name = DefaultName
+	}
+
+	tpl, err := template.New("name").Parse(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid name template: %w", err)
+	}
+
+	// values are used as path parts
+	info.User = safe(info.User)
+	info.SessionID = safe(info.SessionID)
+	info.RemoteAddr = safe(info.RemoteAddr)
+
+	var b strings.Builder
+	if err := tpl.Execute(&b, info); err != nil {
+		return "", fmt.Errorf("name template: %w", err)
+	}
+
+	fileName := filepath.Join(c.Path, filepath.Clean("/"+b.String()))
+	if !strings.HasSuffix(fileName, Ext) {
+		fileName += Ext
+	}
+
+	return fileName, nil
+}
+
+// Record starts a new asciicast v2 recording.
+func (c Cache) Record(info RecordInfo) (*Recorder, error) {
+	fileName, err := c.FileName(info)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fileName), 0o750); err != nil {
+		return nil, fmt.Errorf("create record directory: %w", err)
+	}
+
+	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("create record file: %w", err)
+	}
+
+	r := &Recorder{
+		file:  f,
+		w:     bufio.NewWriter(f),
+		start: info.Time,
+	}
+
+	header := Header{
+		Version:   2,
+		Width:     info.Width,
+		Height:    info.Height,
+		Timestamp: info.Time.Unix(),
+		Title:     fmt.Sprintf("yap %s@%s", info.User, info.RemoteAddr),
+		Env:       map[string]string{"TERM": info.Term},
+	}
+
+	if err := json.NewEncoder(r.w).Encode(header); err != nil {
+		_ = f.Close()
+
+		return nil, fmt.Errorf("write record header: %w", err)
+	}
+
+	go func() {
+		if err := c.Cleanup(); err != nil {
+			log.Warn().Err(err).Msg("failed to cleanup recordings")
+		}
+	}()
+
+	return r, nil
+}
+
+// Cleanup applies the retention limits.
+func (c Cache) Cleanup() error {
+	if !c.Enabled() || (c.MaxAge <= 0 && c.MaxFiles <= 0) {
+		return nil
+	}
+
+	type record struct {
+		path    string
+		modTime time.Time
+	}
+
+	var records []record
+
+	err := filepath.WalkDir(c.Path, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != Ext {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err //nolint:wrapcheck // wrapped below
+		}
+
+		records = append(records, record{path: path, modTime: info.ModTime()})
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walk recordings: %w", err)
+	}
+
+	// newest first
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].modTime.After(records[j].modTime)
+	})
+
+	for i, r := range records {
+		if (c.MaxFiles > 0 && i >= c.MaxFiles) || (c.MaxAge > 0 && time.Since(r.modTime) > c.MaxAge) {
+			if err := os.Remove(r.path); err != nil {
+				return fmt.Errorf("remove recording: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Header is the first line of an asciicast v2 file.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder writes events to the asciicast file.
+type Recorder struct {
+	mutex sync.Mutex
+	file  *os.File
+	w     *bufio.Writer
+	start time.Time
+	// rest is the incomplete utf8 sequence of the last write.
+	rest []byte
+	err  error
+}
+
+// Writer returns a writer that writes to w and records the output.
+func (r *Recorder) Writer(w io.Writer) io.Writer {
+	return teeWriter{w: w, r: r}
+}
+
+type teeWriter struct {
+	w io.Writer
+	r *Recorder
+}
+
+func (t teeWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	t.r.Output(p[:n])
+
+	return n, err //nolint:wrapcheck // writer error
+}
+
+// Output records the data written to the terminal.
+func (r *Recorder) Output(p []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	data := append(r.rest, p...) //nolint:gocritic // rest is owned
+
+	// keep incomplete rune for the next write
+	cut := len(data)
+	for i := 1; i <= utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				cut = len(data) - i
+			}
+
+			break
+		}
+	}
+
+	r.rest = append([]byte(nil), data[cut:]...)
+	r.event("o", string(data[:cut]))
+}
+
+// Resize records the terminal size change.
+func (r *Recorder) Resize(width, height int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.event("r", fmt.Sprintf("%dx%d", width, height))
+}
+
+func (r *Recorder) event(code, data string) {
+	if r.err != nil || data == "" {
+		return
+	}
+
+	v, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), code, data})
+	if err != nil {
+		r.err = err
+		return
+	}
+
+	v = append(v, '\n')
+
+	if _, err := r.w.Write(v); err != nil {
+		r.err = err
+	}
+}
+
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.rest) > 0 {
+		r.event("o", string(bytes.ToValidUTF8(r.rest, nil)))
+		r.rest = nil
+	}
+
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+
+	if r.err != nil {
+		return fmt.Errorf("record: %w", r.err)
+	}
+
+	return nil
 }
 
-func (c Cache) Record() {
+func safe(v string) string {
+	v = unsafeChars.ReplaceAllString(v, "_")
+	if v == "" || strings.Trim(v, ".") == "" {
+		return "_"
+	}
 
+	return v
 }
@@ -0,0 +1,219 @@
+package hold
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRecorderOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   []string
+	}{
+		{
+			name:   "ascii",
+			writes: []string{"hello", " world"},
+			want:   []string{"hello", " world"},
+		},
+		{
+			name:   "two byte rune split",
+			writes: []string{"caf\xc3", "\xa9!"},
+			want:   []string{"caf", "é!"},
+		},
+		{
+			name:   "three byte rune split twice",
+			writes: []string{"\xe2", "\x82", "\xac"},
+			want:   []string{"€"},
+		},
+		{
+			name:   "four byte rune split",
+			writes: []string{"a\xf0\x9f", "\x98\x80b"},
+			want:   []string{"a", "😀b"},
+		},
+		{
+			name:   "incomplete rune on close",
+			writes: []string{"end\xe2\x82"},
+			want:   []string{"end"},
+		},
+		{
+			name:   "invalid byte is not carried",
+			writes: []string{"a\xff", "b"},
+			want:   []string{"a\xff", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Cache{Path: t.TempDir()}
+
+			info := RecordInfo{User: "alice", SessionID: "s1", Time: time.Now(), Width: 80, Height: 24, Term: "xterm"}
+
+			r, err := c.Record(info)
+			if err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+
+			for _, w := range tt.writes {
+				r.Output([]byte(w))
+			}
+
+			if err := r.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			fileName, err := c.FileName(info)
+			if err != nil {
+				t.Fatalf("FileName() error = %v", err)
+			}
+
+			header, events := readRecording(t, fileName)
+			if header.Version != 2 || header.Width != 80 || header.Env["TERM"] != "xterm" {
+				t.Errorf("header = %+v", header)
+			}
+
+			// json replaces the invalid bytes
+			want := make([]string, 0, len(tt.want))
+			for _, w := range tt.want {
+				v, _ := json.Marshal(w)
+
+				var s string
+				_ = json.Unmarshal(v, &s)
+
+				want = append(want, s)
+			}
+
+			if !reflect.DeepEqual(events, want) {
+				t.Errorf("events = %q, want %q", events, want)
+			}
+		})
+	}
+}
+
+func readRecording(t *testing.T, fileName string) (Header, []string) {
+	t.Helper()
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("open recording: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	var header Header
+	if !scanner.Scan() {
+		t.Fatal("recording has no header")
+	}
+
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		t.Fatalf("decode header: %v", err)
+	}
+
+	var events []string
+
+	for scanner.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("decode event: %v", err)
+		}
+
+		if event[1] == "o" {
+			events = append(events, event[2].(string))
+		}
+	}
+
+	return header, events
+}
+
+func TestCacheCleanup(t *testing.T) {
+	now := time.Now()
+
+	// ages of the recordings in hours, the other files are not touched
+	files := map[string]int{
+		"alice/1.cast":   1,
+		"alice/2.cast":   2,
+		"bob/3.cast":     3,
+		"bob/50.cast":    50,
+		"notes.txt":      100,
+		"bob/old.cast.1": 100,
+	}
+
+	tests := []struct {
+		name     string
+		maxAge   time.Duration
+		maxFiles int
+		want     []string
+	}{
+		{
+			name: "no limits",
+			want: []string{"alice/1.cast", "alice/2.cast", "bob/3.cast", "bob/50.cast", "bob/old.cast.1", "notes.txt"},
+		},
+		{
+			name:   "max age",
+			maxAge: 24 * time.Hour,
+			want:   []string{"alice/1.cast", "alice/2.cast", "bob/3.cast", "bob/old.cast.1", "notes.txt"},
+		},
+		{
+			name:     "max files",
+			maxFiles: 2,
+			want:     []string{"alice/1.cast", "alice/2.cast", "bob/old.cast.1", "notes.txt"},
+		},
+		{
+			name:     "both",
+			maxAge:   150 * time.Minute,
+			maxFiles: 3,
+			want:     []string{"alice/1.cast", "alice/2.cast", "bob/old.cast.1", "notes.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			for name, age := range files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(path, nil, 0o640); err != nil {
+					t.Fatal(err)
+				}
+
+				modTime := now.Add(-time.Duration(age) * time.Hour)
+				if err := os.Chtimes(path, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c := Cache{Path: dir, MaxAge: tt.maxAge, MaxFiles: tt.maxFiles}
+			if err := c.Cleanup(); err != nil {
+				t.Fatalf("Cleanup() error = %v", err)
+			}
+
+			var got []string
+
+			_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					rel, _ := filepath.Rel(dir, path)
+					got = append(got, filepath.ToSlash(rel))
+				}
+
+				return nil
+			})
+
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
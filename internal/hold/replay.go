@@ -0,0 +1,91 @@
+package hold
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ReplayConfig is the playback settings of a recording.
+type ReplayConfig struct {
+	// Speed multiplies the playback speed, 1 if not set.
+	Speed float64
+	// MaxIdle limits the waiting time between events, no limit if zero.
+	MaxIdle time.Duration
+}
+
+// Replay writes output events of the asciicast v2 recording to w with the recorded timing.
+func Replay(ctx context.Context, r io.Reader, w io.Writer, cfg ReplayConfig) (*Header, error) {
+	if cfg.Speed <= 0 {
+		cfg.Speed = 1
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("read header: %w", err)
+		}
+
+		return nil, fmt.Errorf("empty recording")
+	}
+
+	var header Header
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("decode header: %w", err)
+	}
+
+	if header.Version != 2 {
+		return nil, fmt.Errorf("unsupported asciicast version %d", header.Version)
+	}
+
+	var last float64
+
+	for line := 2; scanner.Scan(); line++ {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return &header, fmt.Errorf("decode event line %d: %w", line, err)
+		}
+
+		if len(event) != 3 {
+			return &header, fmt.Errorf("invalid event line %d", line)
+		}
+
+		at, _ := event[0].(float64)
+		code, _ := event[1].(string)
+		data, _ := event[2].(string)
+
+		wait := time.Duration((at - last) / cfg.Speed * float64(time.Second))
+		if cfg.MaxIdle > 0 && wait > cfg.MaxIdle {
+			wait = cfg.MaxIdle
+		}
+
+		last = at
+
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return &header, ctx.Err() //nolint:wrapcheck // context error
+			case <-time.After(wait):
+			}
+		}
+
+		if code != "o" {
+			continue
+		}
+
+		if _, err := io.WriteString(w, data); err != nil {
+			return &header, fmt.Errorf("write output: %w", err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return &header, fmt.Errorf("read recording: %w", err)
+	}
+
+	return &header, nil
+}
@@ -0,0 +1,118 @@
+// Package metric holds the Prometheus metrics of yap.
+package metric
+
+import (
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "yap"
+
+// Outcome values of the metrics.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeError   = "error"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	sessionsActive = promauto.With(registry).NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sessions_active",
+		Help:      "Number of active sessions.",
+	})
+
+	sessionDuration = promauto.With(registry).NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "session_duration_seconds",
+		Help:      "Duration of the finished sessions.",
+		// 1s to 4.5h
+		Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+	})
+
+	loginAttempts = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_attempts_total",
+		Help:      "Login attempts by tab and outcome.",
+	}, []string{"tab", "outcome"})
+
+	screenTransitions = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "screen_transitions_total",
+		Help:      "Transitions to the views by view ID.",
+	}, []string{"view"})
+
+	commandExecutions = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "command_executions_total",
+		Help:      "Command executions by command name and outcome.",
+	}, []string{"command", "outcome"})
+
+	bytesWritten = promauto.With(registry).NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bytes_written_total",
+		Help:      "Bytes written to the session terminals.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// SessionStarted counts an active session, returned function should be called
+// when the session ends.
+func SessionStarted() func() {
+	start := time.Now()
+
+	sessionsActive.Inc()
+
+	return func() {
+		sessionsActive.Dec()
+		sessionDuration.Observe(time.Since(start).Seconds())
+	}
+}
+
+// LoginAttempt counts a login attempt of the tab, nil error is a success.
+func LoginAttempt(tab string, err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeFailure
+	}
+
+	loginAttempts.WithLabelValues(tab, outcome).Inc()
+}
+
+// Transition counts a visit of the view.
+func Transition(view string) {
+	screenTransitions.WithLabelValues(view).Inc()
+}
+
+// CommandExecution counts a finished command.
+func CommandExecution(command, outcome string) {
+	commandExecutions.WithLabelValues(command, outcome).Inc()
+}
+
+// Writer counts the bytes written to w.
+func Writer(w io.Writer) io.Writer {
+	return countWriter{w: w}
+}
+
+type countWriter struct {
+	w io.Writer
+}
+
+func (c countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	bytesWritten.Add(float64(n))
+
+	return n, err //nolint:wrapcheck // writer error
+}
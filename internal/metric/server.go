@@ -0,0 +1,81 @@
+package metric
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+	"github.com/rytsh/liz/utils/shutdown"
+)
+
+var ShutdownTimeout = 5 * time.Second
+
+// Server is the HTTP listener of the metrics.
+type Server struct {
+	// Addr is the listen address like ":9090", metrics are disabled if empty.
+	Addr string `cfg:"addr"`
+	// Path of the metrics endpoint, default is /metrics.
+	Path string `cfg:"path"`
+}
+
+func (s Server) Enabled() bool {
+	return s.Addr != ""
+}
+
+func (s Server) path() string {
+	if s.Path == "" {
+		return "/metrics"
+	}
+
+	return s.Path
+}
+
+// Serve starts the metrics listener if it is enabled.
+func Serve(wg *sync.WaitGroup, cfg Server) error {
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(cfg.path(), promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("could not listen metrics: %w", err)
+	}
+
+	log.Info().Msgf("serving metrics on %s%s", listener.Addr(), cfg.path())
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("metrics server stopped")
+		}
+	}()
+
+	shutdown.Global.Add("metrics server", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+
+		if err := srv.Shutdown(ctx); err != nil {
+			return fmt.Errorf("could not stop metrics server: %w", err)
+		}
+
+		return nil
+	})
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+	"github.com/rs/zerolog/log"
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/tui/model"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+type ctxKey string
+
+const (
+	// ctxKeyIdentity holds the user matched by public key authentication.
+	ctxKeyIdentity ctxKey = "identity"
+	// ctxKeyRoles holds the roles in the comment of the matched key.
+	ctxKeyRoles ctxKey = "roles"
+	// ctxKeyFingerprint holds the fingerprint of the matched key.
+	ctxKeyFingerprint ctxKey = "fingerprint"
+)
+
+// PublicKeyAuth is an authorized_keys style configuration for the SSH layer.
+// A "roles=admin,dev" word in the comment of a key gives the roles to the identity.
+type PublicKeyAuth struct {
+	// AuthorizedKeysFile is a path of an authorized_keys file, identity is the SSH user.
+	AuthorizedKeysFile string `cfg:"authorized_keys_file"`
+	// Keys are inline authorized keys, identity is the SSH user.
+	Keys []string `cfg:"keys"`
+	// Users are per user key lists, identity is the user name.
+	Users map[string][]string `cfg:"users"`
+	// Fallback lets clients without a matching key in, login form should handle them.
+	Fallback bool `cfg:"fallback"`
+
+	keys  []authorizedKey            `cfg:"-"`
+	users map[string][]authorizedKey `cfg:"-"`
+}
+
+type authorizedKey struct {
+	key   ssh.PublicKey
+	roles []string
+}
+
+func (p *PublicKeyAuth) Enabled() bool {
+	return p.AuthorizedKeysFile != "" || len(p.Keys) > 0 || len(p.Users) > 0
+}
+
+func (p *PublicKeyAuth) Prepare() error {
+	p.keys = make([]authorizedKey, 0, len(p.Keys))
+	for _, k := range p.Keys {
+		key, err := parseKey(k)
+		if err != nil {
+			return err
+		}
+
+		p.keys = append(p.keys, key)
+	}
+
+	// a key should resolve to one identity
+	owners := make(map[string]string)
+
+	p.users = make(map[string][]authorizedKey, len(p.Users))
+	for user, keys := range p.Users {
+		for _, k := range keys {
+			key, err := parseKey(k)
+			if err != nil {
+				return fmt.Errorf("user %q: %w", user, err)
+			}
+
+			id := string(key.key.Marshal())
+			if owner, ok := owners[id]; ok && owner != user {
+				first, second := owner, user
+				if second < first {
+					first, second = second, first
+				}
+
+				return fmt.Errorf("key %s is listed under users %q and %q", gossh.FingerprintSHA256(key.key), first, second)
+			}
+
+			owners[id] = user
+			p.users[user] = append(p.users[user], key)
+		}
+	}
+
+	if p.AuthorizedKeysFile != "" {
+		if _, err := os.Stat(p.AuthorizedKeysFile); err != nil {
+			return fmt.Errorf("authorized keys file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Identity returns matched identity and roles of the key, empty if key is not authorized.
+func (p *PublicKeyAuth) Identity(user string, key ssh.PublicKey) (string, []string) {
+	for name, keys := range p.users {
+		for _, k := range keys {
+			if ssh.KeysEqual(key, k.key) {
+				return name, k.roles
+			}
+		}
+	}
+
+	for _, k := range p.keys {
+		if ssh.KeysEqual(key, k.key) {
+			return user, k.roles
+		}
+	}
+
+	if p.AuthorizedKeysFile != "" {
+		if roles, ok := p.inFile(key); ok {
+			return user, roles
+		}
+	}
+
+	return "", nil
+}
+
+// inFile reads file on every check, so changes are applied without restart.
+func (p *PublicKeyAuth) inFile(key ssh.PublicKey) ([]string, bool) {
+	content, err := os.ReadFile(p.AuthorizedKeysFile)
+	if err != nil {
+		log.Warn().Err(err).Str("path", p.AuthorizedKeysFile).Msg("failed to read authorized keys")
+		return nil, false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		k, comment, _, _, err := ssh.ParseAuthorizedKey(line)
+		if err != nil {
+			log.Warn().Err(err).Str("path", p.AuthorizedKeysFile).Msg("failed to parse authorized key")
+			continue
+		}
+
+		if ssh.KeysEqual(key, k) {
+			return commentRoles(comment), true
+		}
+	}
+
+	return nil, false
+}
+
+// Options returns ssh options to set authentication handlers.
+func (p *PublicKeyAuth) Options() []ssh.Option {
+	if !p.Enabled() {
+		return nil
+	}
+
+	opts := []ssh.Option{
+		// callback runs for the queried and the signed keys, the last call is
+		// the accepted key so success is logged when the session starts
+		wish.WithPublicKeyAuth(func(ctx ssh.Context, key ssh.PublicKey) bool {
+			identity, roles := p.Identity(ctx.User(), key)
+
+			if identity == "" {
+				audit.Log(audit.Event{
+					Type:       audit.TypeAuth,
+					SessionID:  ctx.SessionID(),
+					RemoteAddr: ctx.RemoteAddr().String(),
+					User:       ctx.User(),
+					Method:     model.MethodPublicKey,
+					Params:     map[string]interface{}{"fingerprint": gossh.FingerprintSHA256(key)},
+					Result:     audit.ResultFailure,
+				})
+
+				return false
+			}
+
+			ctx.SetValue(ctxKeyIdentity, identity)
+			ctx.SetValue(ctxKeyRoles, roles)
+			ctx.SetValue(ctxKeyFingerprint, gossh.FingerprintSHA256(key))
+
+			return true
+		}),
+	}
+
+	if p.Fallback {
+		opts = append(opts, wish.WithKeyboardInteractiveAuth(func(ctx ssh.Context, _ gossh.KeyboardInteractiveChallenge) bool {
+			// public key is not used, clear checked one
+			ctx.SetValue(ctxKeyIdentity, "")
+			ctx.SetValue(ctxKeyRoles, []string(nil))
+			ctx.SetValue(ctxKeyFingerprint, "")
+
+			return true
+		}))
+	}
+
+	return opts
+}
+
+func parseKey(k string) (authorizedKey, error) {
+	key, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(k))
+	if err != nil {
+		return authorizedKey{}, fmt.Errorf("invalid key %q: %w", k, err)
+	}
+
+	return authorizedKey{key: key, roles: commentRoles(comment)}, nil
+}
+
+// commentRoles returns the roles of the "roles=a,b" word in the key comment.
+func commentRoles(comment string) []string {
+	for _, word := range strings.Fields(comment) {
+		v, ok := strings.CutPrefix(word, "roles=")
+		if !ok {
+			continue
+		}
+
+		var roles []string
+
+		for _, r := range strings.Split(v, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				roles = append(roles, r)
+			}
+		}
+
+		return roles
+	}
+
+	return nil
+}
+
+func newSession(s ssh.Session) *model.Session {
+	session := model.NewSession(s.Context().SessionID(), s.User(), s.RemoteAddr().String())
+
+	if v, _ := s.Context().Value(ctxKeyIdentity).(string); v != "" {
+		session.SetIdentity(v, model.MethodPublicKey)
+
+		roles, _ := s.Context().Value(ctxKeyRoles).([]string)
+		session.SetRoles(roles)
+
+		session.Fingerprint, _ = s.Context().Value(ctxKeyFingerprint).(string)
+	}
+
+	return session
+}
+
+// auditPublicKey logs the accepted key of the session.
+func auditPublicKey(s ssh.Session, session *model.Session) {
+	if session.Method() != model.MethodPublicKey {
+		return
+	}
+
+	fingerprint, _ := s.Context().Value(ctxKeyFingerprint).(string)
+
+	e := audit.Event{
+		Type:   audit.TypeAuth,
+		Params: map[string]interface{}{"fingerprint": fingerprint},
+		Result: audit.ResultSuccess,
+	}
+
+	if roles := session.Roles(); len(roles) > 0 {
+		e.Params["roles"] = roles
+	}
+
+	session.Audit(e)
+}
@@ -0,0 +1,335 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/charmbracelet/keygen"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func newSigner(t *testing.T) gossh.Signer {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := gossh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer
+}
+
+// authorizedLine returns the authorized_keys line of the key with the comment.
+func authorizedLine(s gossh.Signer, comment string) string {
+	return strings.TrimSpace(strings.TrimSuffix(string(gossh.MarshalAuthorizedKey(s.PublicKey())), "\n") + " " + comment)
+}
+
+// fakeContext is the context of a connection without a client.
+type fakeContext struct {
+	context.Context
+	sync.Mutex
+	user string
+}
+
+func (c *fakeContext) User() string          { return c.user }
+func (c *fakeContext) SessionID() string     { return "1" }
+func (c *fakeContext) ClientVersion() string { return "" }
+func (c *fakeContext) ServerVersion() string { return "" }
+func (c *fakeContext) RemoteAddr() net.Addr  { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+func (c *fakeContext) LocalAddr() net.Addr   { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+func (c *fakeContext) Permissions() *ssh.Permissions {
+	return &ssh.Permissions{Permissions: &gossh.Permissions{}}
+}
+func (c *fakeContext) SetValue(key, value interface{}) {
+	c.Context = context.WithValue(c.Context, key, value)
+}
+
+// serveAuth starts a server with the auth, sessions write the identity, the
+// roles and the fingerprint of the session.
+func serveAuth(t *testing.T, p *PublicKeyAuth) string {
+	t.Helper()
+
+	if err := p.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	hostKey, err := keygen.New("", keygen.WithKeyType(keygen.Ed25519))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := []ssh.Option{
+		wish.WithHostKeyPEM(hostKey.RawPrivateKey()),
+		wish.WithMiddleware(func(ssh.Handler) ssh.Handler {
+			return func(s ssh.Session) {
+				session := newSession(s)
+
+				fmt.Fprintf(s, "%s|%s|%s", session.Identity(), strings.Join(session.Roles(), ","), session.Fingerprint)
+			}
+		}),
+	}
+
+	s, err := wish.NewServer(append(opts, p.Options()...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() { _ = s.Serve(l) }()
+
+	t.Cleanup(func() { _ = s.Close() })
+
+	return l.Addr().String()
+}
+
+// login returns the output of the session, the error of the handshake.
+func login(addr, user string, auth ...gossh.AuthMethod) (string, error) {
+	client, err := gossh.Dial("tcp", addr, &gossh.ClientConfig{
+		User:            user,
+		Auth:            auth,
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(), //nolint:gosec // test server
+	})
+	if err != nil {
+		return "", err //nolint:wrapcheck // test
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err //nolint:wrapcheck // test
+	}
+	defer session.Close()
+
+	out, err := session.Output("")
+	if err != nil && err != io.EOF {
+		if _, ok := err.(*gossh.ExitMissingError); !ok {
+			return string(out), err //nolint:wrapcheck // test
+		}
+	}
+
+	return string(out), nil
+}
+
+func keyboardInteractive() gossh.AuthMethod {
+	return gossh.KeyboardInteractive(func(string, string, []string, []bool) ([]string, error) {
+		return nil, nil
+	})
+}
+
+func TestPublicKeyAuth(t *testing.T) {
+	alice := newSigner(t)
+	bob := newSigner(t)
+	carol := newSigner(t)
+	stranger := newSigner(t)
+
+	file := filepath.Join(t.TempDir(), "authorized_keys")
+	content := "# carol\n" + authorizedLine(carol, "carol@laptop roles=ops") + "\n"
+
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint := func(s gossh.Signer) string { return gossh.FingerprintSHA256(s.PublicKey()) }
+
+	tests := []struct {
+		name     string
+		fallback bool
+		user     string
+		auth     []gossh.AuthMethod
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "user key is the user",
+			user: "root",
+			auth: []gossh.AuthMethod{gossh.PublicKeys(alice)},
+			want: "alice|admin,dev|" + fingerprint(alice),
+		},
+		{
+			name: "inline key is the SSH user",
+			user: "deploy",
+			auth: []gossh.AuthMethod{gossh.PublicKeys(bob)},
+			want: "deploy||" + fingerprint(bob),
+		},
+		{
+			name: "key of the file",
+			user: "carol",
+			auth: []gossh.AuthMethod{gossh.PublicKeys(carol)},
+			want: "carol|ops|" + fingerprint(carol),
+		},
+		{
+			name:    "unknown key",
+			user:    "alice",
+			auth:    []gossh.AuthMethod{gossh.PublicKeys(stranger)},
+			wantErr: true,
+		},
+		{
+			name:    "keyboard interactive without fallback",
+			user:    "alice",
+			auth:    []gossh.AuthMethod{keyboardInteractive()},
+			wantErr: true,
+		},
+		{
+			name:     "unknown key with fallback",
+			fallback: true,
+			user:     "alice",
+			auth:     []gossh.AuthMethod{gossh.PublicKeys(stranger), keyboardInteractive()},
+			want:     "||",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := serveAuth(t, &PublicKeyAuth{
+				AuthorizedKeysFile: file,
+				Keys:               []string{authorizedLine(bob, "bob@laptop")},
+				Users:              map[string][]string{"alice": {authorizedLine(alice, "roles=admin,dev")}},
+				Fallback:           tt.fallback,
+			})
+
+			got, err := login(addr, tt.user, tt.auth...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("login() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("session = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublicKeyAuthFallback(t *testing.T) {
+	alice := newSigner(t)
+
+	p := PublicKeyAuth{
+		Users:    map[string][]string{"alice": {authorizedLine(alice, "roles=admin")}},
+		Fallback: true,
+	}
+	if err := p.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	s := &ssh.Server{}
+	for _, opt := range p.Options() {
+		if err := opt(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := &fakeContext{Context: context.Background(), user: "root"}
+
+	// the key is queried, then the client can not sign and falls back
+	if !s.PublicKeyHandler(ctx, alice.PublicKey()) {
+		t.Fatal("key of the user is not accepted")
+	}
+
+	if v, _ := ctx.Value(ctxKeyIdentity).(string); v != "alice" {
+		t.Fatalf("identity after the key = %q, want alice", v)
+	}
+
+	if !s.KeyboardInteractiveHandler(ctx, nil) {
+		t.Fatal("keyboard interactive is not accepted")
+	}
+
+	identity, _ := ctx.Value(ctxKeyIdentity).(string)
+	roles, _ := ctx.Value(ctxKeyRoles).([]string)
+	fingerprint, _ := ctx.Value(ctxKeyFingerprint).(string)
+
+	if identity != "" || roles != nil || fingerprint != "" {
+		t.Errorf("fallback keeps the key: identity %q, roles %q, fingerprint %q", identity, roles, fingerprint)
+	}
+}
+
+func TestPublicKeyAuthPrepare(t *testing.T) {
+	alice := newSigner(t)
+	bob := newSigner(t)
+
+	tests := []struct {
+		name    string
+		auth    PublicKeyAuth
+		wantErr string
+	}{
+		{
+			name: "same key twice for a user",
+			auth: PublicKeyAuth{Users: map[string][]string{"alice": {authorizedLine(alice, ""), authorizedLine(alice, "roles=admin")}}},
+		},
+		{
+			name: "key of two users",
+			auth: PublicKeyAuth{Users: map[string][]string{
+				"bob":   {authorizedLine(bob, ""), authorizedLine(alice, "")},
+				"alice": {authorizedLine(alice, "")},
+			}},
+			wantErr: fmt.Sprintf("key %s is listed under users %q and %q", gossh.FingerprintSHA256(alice.PublicKey()), "alice", "bob"),
+		},
+		{
+			name:    "invalid user key",
+			auth:    PublicKeyAuth{Users: map[string][]string{"alice": {"ssh-ed25519 broken"}}},
+			wantErr: `user "alice": invalid key`,
+		},
+		{
+			name:    "invalid key",
+			auth:    PublicKeyAuth{Keys: []string{"not a key"}},
+			wantErr: "invalid key",
+		},
+		{
+			name:    "missing file",
+			auth:    PublicKeyAuth{AuthorizedKeysFile: filepath.Join(t.TempDir(), "missing")},
+			wantErr: "authorized keys file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.auth.Prepare()
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Prepare() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Prepare() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommentRoles(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    []string
+	}{
+		{comment: "", want: nil},
+		{comment: "alice@laptop", want: nil},
+		{comment: "alice@laptop roles=admin,dev", want: []string{"admin", "dev"}},
+		{comment: "roles=ops,,  extra", want: []string{"ops"}},
+		{comment: "roles= roles=admin", want: nil},
+		{comment: "myroles=admin", want: nil},
+	}
+
+	for _, tt := range tests {
+		got := commentRoles(tt.comment)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") || len(got) != len(tt.want) {
+			t.Errorf("commentRoles(%q) = %q, want %q", tt.comment, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/keygen"
+	"github.com/charmbracelet/ssh"
+	"github.com/rs/zerolog/log"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// HostKey is the host key configuration of the SSH server.
+type HostKey struct {
+	// Files are private key files, missing files are generated.
+	Files []HostKeyFile `cfg:"files"`
+	// PEM is private key content with one or more PEM blocks, load it with vault.
+	PEM string `cfg:"pem" loggable:"false"`
+}
+
+type HostKeyFile struct {
+	Path string `cfg:"path"`
+	// Type of the generated key; ed25519, rsa or ecdsa. Default is ed25519.
+	Type string `cfg:"type"`
+}
+
+// LegacyHostKeyPath is the default host key of the older versions, relative to
+// the working directory.
+const LegacyHostKeyPath = ".ssh/term_info_ed25519"
+
+// DefaultHostKeyFile returns the host key file used when no key is configured.
+// It is in the user config directory to keep the same key in every working directory,
+// the legacy key is used if it exists so clients do not see a changed host key.
+func DefaultHostKeyFile() HostKeyFile {
+	if _, err := os.Stat(LegacyHostKeyPath); err == nil {
+		return HostKeyFile{
+			Path: LegacyHostKeyPath,
+			Type: string(keygen.Ed25519),
+		}
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+
+	return HostKeyFile{
+		Path: filepath.Join(dir, "yap", "ssh_host_ed25519_key"),
+		Type: string(keygen.Ed25519),
+	}
+}
+
+// Prepare checks the key types and the PEM content.
+func (h *HostKey) Prepare() error {
+	for i, f := range h.Files {
+		if f.Path == "" {
+			return fmt.Errorf("files[%d]: path is required", i)
+		}
+
+		if _, err := f.keyType(); err != nil {
+			return fmt.Errorf("files[%d]: %w", i, err)
+		}
+	}
+
+	if _, err := h.pemSigners(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Signers loads the host keys, key files are generated if they do not exist.
+func (h *HostKey) Signers() ([]gossh.Signer, error) {
+	signers, err := h.pemSigners()
+	if err != nil {
+		return nil, err
+	}
+
+	files := h.Files
+	if len(files) == 0 && len(signers) == 0 {
+		files = []HostKeyFile{DefaultHostKeyFile()}
+	}
+
+	for _, f := range files {
+		signer, err := f.signer()
+		if err != nil {
+			return nil, err
+		}
+
+		signers = append(signers, signer)
+	}
+
+	return signers, nil
+}
+
+// Options returns the host key options of the server and logs the fingerprints.
+func (h *HostKey) Options() ([]ssh.Option, error) {
+	signers, err := h.Signers()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := make([]ssh.Option, 0, len(signers))
+	for _, s := range signers {
+		log.Info().Str("type", s.PublicKey().Type()).Msgf("host key %s", gossh.FingerprintSHA256(s.PublicKey()))
+
+		opts = append(opts, func(signer gossh.Signer) ssh.Option {
+			return func(srv *ssh.Server) error {
+				srv.AddHostKey(signer)
+
+				return nil
+			}
+		}(s))
+	}
+
+	return opts, nil
+}
+
+func (h *HostKey) pemSigners() ([]gossh.Signer, error) {
+	var signers []gossh.Signer
+
+	rest := []byte(h.PEM)
+	for {
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		signer, err := gossh.ParsePrivateKey(pem.EncodeToMemory(block))
+		if err != nil {
+			return nil, fmt.Errorf("pem: %w", err)
+		}
+
+		signers = append(signers, signer)
+	}
+
+	if len(signers) == 0 && h.PEM != "" {
+		return nil, errors.New("pem: no private key found")
+	}
+
+	return signers, nil
+}
+
+func (f HostKeyFile) keyType() (keygen.KeyType, error) {
+	switch keygen.KeyType(f.Type) {
+	case "", keygen.Ed25519:
+		return keygen.Ed25519, nil
+	case keygen.RSA:
+		return keygen.RSA, nil
+	case keygen.ECDSA:
+		return keygen.ECDSA, nil
+	}
+
+	return "", fmt.Errorf("unsupported key type %q", f.Type)
+}
+
+// signer loads the private key, a new key is generated only if the file does not exist.
+func (f HostKeyFile) signer() (gossh.Signer, error) {
+	content, err := os.ReadFile(f.Path)
+	if err == nil {
+		signer, err := gossh.ParsePrivateKey(content)
+		if err != nil {
+			return nil, fmt.Errorf("host key %s: %w", f.Path, err)
+		}
+
+		return signer, nil
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("host key %s: %w", f.Path, err)
+	}
+
+	keyType, err := f.keyType()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o700); err != nil {
+		return nil, fmt.Errorf("create host key directory: %w", err)
+	}
+
+	pair, err := keygen.New(f.Path, keygen.WithKeyType(keyType))
+	if err != nil {
+		return nil, fmt.Errorf("host key %s: %w", f.Path, err)
+	}
+
+	if err := pair.WriteKeys(); err != nil {
+		return nil, fmt.Errorf("host key %s: %w", f.Path, err)
+	}
+
+	log.Warn().Msgf("generated new %s host key %s", keyType, f.Path)
+
+	return pair.Signer(), nil
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/keygen"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestHostKeyFileSigner(t *testing.T) {
+	existing, err := keygen.New("", keygen.WithKeyType(keygen.Ed25519))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// files are written before loading, relative to the key path
+		files       map[string][]byte
+		keyType     string
+		wantKey     gossh.PublicKey
+		wantType    string
+		wantErr     bool
+		wantPubFile bool
+	}{
+		{
+			name:        "missing key is generated",
+			wantType:    gossh.KeyAlgoED25519,
+			wantPubFile: true,
+		},
+		{
+			name:        "generated with the type",
+			keyType:     string(keygen.ECDSA),
+			wantType:    gossh.KeyAlgoECDSA384,
+			wantPubFile: true,
+		},
+		{
+			name:    "private key without public key",
+			files:   map[string][]byte{"": existing.RawPrivateKey()},
+			wantKey: existing.PublicKey(),
+		},
+		{
+			name:    "existing key ignores the type",
+			files:   map[string][]byte{"": existing.RawPrivateKey()},
+			keyType: string(keygen.RSA),
+			wantKey: existing.PublicKey(),
+		},
+		{
+			name:    "invalid key is not replaced",
+			files:   map[string][]byte{"": []byte("not a key")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys", "ssh_host_key")
+
+			for suffix, content := range tt.files {
+				if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(path+suffix, content, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			signer, err := HostKeyFile{Path: path, Type: tt.keyType}.signer()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("signer() want error")
+				}
+
+				if content, _ := os.ReadFile(path); string(content) != string(tt.files[""]) {
+					t.Error("invalid key file is changed")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("signer() error = %v", err)
+			}
+
+			if tt.wantKey != nil && gossh.FingerprintSHA256(signer.PublicKey()) != gossh.FingerprintSHA256(tt.wantKey) {
+				t.Errorf("signer() loaded another key")
+			}
+
+			if tt.wantType != "" && signer.PublicKey().Type() != tt.wantType {
+				t.Errorf("key type = %s, want %s", signer.PublicKey().Type(), tt.wantType)
+			}
+
+			if _, err := os.Stat(path + ".pub"); (err == nil) != tt.wantPubFile {
+				t.Errorf("public key file exists = %v, want %v", err == nil, tt.wantPubFile)
+			}
+
+			// same key on the next start
+			again, err := HostKeyFile{Path: path}.signer()
+			if err != nil {
+				t.Fatalf("signer() again error = %v", err)
+			}
+
+			if gossh.FingerprintSHA256(again.PublicKey()) != gossh.FingerprintSHA256(signer.PublicKey()) {
+				t.Error("signer() again loaded another key")
+			}
+		})
+	}
+}
+
+func TestDefaultHostKeyFileLegacy(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	if got := DefaultHostKeyFile().Path; got == LegacyHostKeyPath {
+		t.Fatalf("DefaultHostKeyFile() = %s without the legacy key", got)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(LegacyHostKeyPath), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(LegacyHostKeyPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := DefaultHostKeyFile().Path; got != LegacyHostKeyPath {
+		t.Errorf("DefaultHostKeyFile() = %s, want %s", got, LegacyHostKeyPath)
+	}
+}
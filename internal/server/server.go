@@ -12,6 +12,7 @@ import (
 	lm "github.com/charmbracelet/wish/logging"
 	"github.com/rs/zerolog/log"
 	"github.com/rytsh/liz/utils/shutdown"
+	"github.com/rytsh/yap/internal/hold"
 	"github.com/rytsh/yap/internal/tui"
 )
 
@@ -21,18 +22,38 @@ type Config struct {
 	Host string
 	Port int
 
-	Screen tui.Screen
+	HostKey   HostKey
+	PublicKey PublicKeyAuth
+	Record    hold.Cache
+	// Timeout closes the idle and the long sessions.
+	Timeout tui.Timeout
+
+	// Screen is loaded for every new session, it can be swapped while serving.
+	Screen *tui.Store
 }
 
 func Serve(wg *sync.WaitGroup, cfg Config) error {
-	s, err := wish.NewServer(
+	if err := cfg.PublicKey.Prepare(); err != nil {
+		return fmt.Errorf("could not prepare public key auth: %w", err)
+	}
+
+	hostKeys, err := cfg.HostKey.Options()
+	if err != nil {
+		return fmt.Errorf("could not load host keys: %w", err)
+	}
+
+	opts := []ssh.Option{
 		wish.WithAddress(fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)),
-		wish.WithHostKeyPath(".ssh/term_info_ed25519"),
 		wish.WithMiddleware(
-			screenMiddleware(cfg.Screen),
+			screenMiddleware(cfg.Screen, cfg.Record, cfg.Timeout),
 			lm.Middleware(),
 		),
-	)
+	}
+
+	opts = append(opts, hostKeys...)
+	opts = append(opts, cfg.PublicKey.Options()...)
+
+	s, err := wish.NewServer(opts...)
 	if err != nil {
 		return fmt.Errorf("could not create server: %w", err)
 	}
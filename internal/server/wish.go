@@ -1,48 +1,112 @@
 package server
 
 import (
+	"io"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/ssh"
 	"github.com/charmbracelet/wish"
-	bm "github.com/charmbracelet/wish/bubbletea"
 	"github.com/muesli/termenv"
+	"github.com/rs/zerolog/log"
 
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/hold"
+	"github.com/rytsh/yap/internal/metric"
 	"github.com/rytsh/yap/internal/tui"
-	"github.com/rytsh/yap/internal/tui/model"
 )
 
-// You can write your own custom bubbletea middleware that wraps tea.Program.
-// Make sure you set the program input and output to ssh.Session.
-func screenMiddleware(screen tui.Screen) wish.Middleware {
-	newProg := func(m tea.Model, opts ...tea.ProgramOption) *tea.Program {
-		p := tea.NewProgram(m, opts...)
-		go func() {
-			for {
-				<-time.After(1 * time.Second)
-				p.Send(model.TimeMsg(time.Now()))
+// screenMiddleware runs the screen as a tea.Program for every session.
+// Same as bubbletea middleware of wish, with recording of the session.
+func screenMiddleware(screen *tui.Store, record hold.Cache, timeout tui.Timeout) wish.Middleware {
+	return func(sh ssh.Handler) ssh.Handler {
+		lipgloss.SetColorProfile(termenv.ANSI256)
+
+		return func(s ssh.Session) {
+			defer sh(s)
+
+			pty, windowChanges, active := s.Pty()
+			if !active {
+				wish.Fatalln(s, "no active terminal, skipping")
+				return
 			}
-		}()
-		return p
-	}
 
-	teaHandler := func(s ssh.Session) *tea.Program {
-		pty, _, active := s.Pty()
-		if !active {
-			wish.Fatalln(s, "no active terminal, skipping")
-			return nil
-		}
+			session := newSession(s)
+			session.Cache = record
 
-		m := model.IndexModel{
-			Width:  pty.Window.Width,
-			Height: pty.Window.Height,
+			// stops the processes of the session on disconnect
+			defer session.Close()
 
-			Models: screen.Models(),
-		}
+			defer metric.SessionStarted()()
 
-		return newProg(m.SetModels(), tea.WithInput(s), tea.WithOutput(s), tea.WithAltScreen())
-	}
+			auditPublicKey(s, session)
+			session.Audit(audit.Event{Type: audit.TypeConnect, Params: map[string]interface{}{"term": pty.Term}})
+			defer func() {
+				session.Audit(audit.Event{Type: audit.TypeDisconnect, Duration: time.Since(session.Start).Seconds()})
+			}()
+
+			var output io.Writer = metric.Writer(s)
+
+			var recorder *hold.Recorder
+			if record.Enabled() {
+				var err error
+				recorder, err = record.Record(hold.RecordInfo{
+					User:       s.User(),
+					SessionID:  session.ID,
+					RemoteAddr: session.RemoteAddr,
+					Time:       session.Start,
+					Width:      pty.Window.Width,
+					Height:     pty.Window.Height,
+					Term:       pty.Term,
+				})
+				if err != nil {
+					log.Error().Err(err).Msg("could not start recording")
+					wish.Fatalln(s, "could not start recording")
+					return
+				}
+
+				defer func() {
+					if err := recorder.Close(); err != nil {
+						log.Error().Err(err).Msg("could not close recording")
+					}
+				}()
+
+				output = recorder.Writer(output)
+			}
 
-	return bm.MiddlewareWithProgramHandler(teaHandler, termenv.ANSI256)
+			// session keeps the screen snapshot even if it is reloaded
+			m := screen.Load().Start(session, pty.Window.Width, pty.Window.Height)
+
+			p := tui.NewProgram(session, m, timeout, tea.WithInput(s), tea.WithOutput(output), tea.WithAltScreen())
+
+			go func() {
+				for {
+					select {
+					case <-s.Context().Done():
+						p.Quit()
+						return
+					case w := <-windowChanges:
+						if recorder != nil {
+							recorder.Resize(w.Width, w.Height)
+						}
+
+						p.Send(tea.WindowSizeMsg{Width: w.Width, Height: w.Height})
+					}
+				}
+			}()
+
+			final, err := p.Run()
+			if err != nil {
+				log.Error().Err(err).Msg("app exit with error")
+			}
+
+			// restore the terminal in case of a tui crash
+			p.Kill()
+
+			if err := tui.Closed(final); err != nil {
+				wish.Println(s, err.Error())
+			}
+		}
+	}
 }
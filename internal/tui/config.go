@@ -1,10 +1,21 @@
 package tui
 
 import (
+	"errors"
+	"fmt"
+
+	"github.com/rytsh/yap/internal/check"
 	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/style"
+	"github.com/rytsh/yap/internal/tui/view/command"
+	"github.com/rytsh/yap/internal/tui/view/form"
 	"github.com/rytsh/yap/internal/tui/view/login"
+	"github.com/rytsh/yap/internal/tui/view/markdown"
+	"github.com/rytsh/yap/internal/tui/view/menu"
 )
 
+var ErrEmptyScreen = errors.New("screen has no views")
+
 type Screen []View
 
 type View struct {
@@ -13,12 +24,42 @@ type View struct {
 	Prev string `cfg:"prev"`
 	Up   string `cfg:"up"`
 	Down string `cfg:"down"`
+	// Theme of the view, global theme is used if empty.
+	Theme string `cfg:"theme"`
+	// Roles are required to open the view, one of them is enough.
+	Roles []string `cfg:"roles"`
 
 	Selection Selection `cfg:"selection"`
+
+	theme  style.Theme
+	styles *style.Styles
+}
+
+// Key returns ID of the view, generated from the position if not set.
+func (v View) Key(i int) string {
+	if v.ID != "" {
+		return v.ID
+	}
+
+	return fmt.Sprintf("view-%d", i)
+}
+
+func (v View) Links() map[model.Direction]string {
+	return map[model.Direction]string{
+		model.Next: v.Next,
+		model.Prev: v.Prev,
+		model.Up:   v.Up,
+		model.Down: v.Down,
+	}
 }
 
 type Selection struct {
-	Login *login.Action `cfg:"login"`
+	Login   *login.Action   `cfg:"login"`
+	Command *command.Action `cfg:"command"`
+	Menu    *menu.Action    `cfg:"menu"`
+	Form    *form.Action    `cfg:"form"`
+	// Markdown shows a document, links to view IDs are targets of the view.
+	Markdown *markdown.Action `cfg:"markdown"`
 }
 
 func (s Selection) Action() model.Model {
@@ -26,15 +67,205 @@ func (s Selection) Action() model.Model {
 		return login.NewLoginModel(*s.Login)
 	}
 
+	if s.Command != nil {
+		return command.NewCommandModel(*s.Command)
+	}
+
+	if s.Menu != nil {
+		return menu.NewMenuModel(*s.Menu)
+	}
+
+	if s.Form != nil {
+		return form.NewFormModel(*s.Form)
+	}
+
+	if s.Markdown != nil {
+		return markdown.NewMarkdownModel(*s.Markdown)
+	}
+
 	return nil
 }
 
-func (s Screen) Models() []model.Model {
-	var models []model.Model
+// Validate checks the selection and prepares the actions.
+func (s Selection) Validate() error {
+	var issues check.Issues
+
+	count := 0
+
+	if s.Login != nil {
+		count++
+		issues.Add("login", s.Login.Validate())
+	}
+
+	if s.Command != nil {
+		count++
+		issues.Add("command", s.Command.Validate())
+	}
+
+	if s.Menu != nil {
+		count++
+		issues.Add("menu", s.Menu.Validate())
+	}
+
+	if s.Form != nil {
+		count++
+		issues.Add("form", s.Form.Validate())
+	}
+
+	if s.Markdown != nil {
+		count++
+		issues.Add("markdown", s.Markdown.Validate())
+	}
+
+	switch {
+	case count == 0:
+		issues.Addf("", "selection is empty")
+	case count > 1:
+		issues.Addf("", "only one selection is allowed")
+	}
+
+	return issues.Err()
+}
+
+// Dynamic returns true if the selection has templated targets, they can go to any view.
+func (s Selection) Dynamic() bool {
+	return s.Menu != nil && s.Menu.Dynamic()
+}
+
+// Targets returns view IDs that the selection can go to.
+func (s Selection) Targets() []string {
+	if s.Menu != nil {
+		return s.Menu.Targets()
+	}
+
+	if s.Markdown != nil {
+		return s.Markdown.Targets()
+	}
+
+	return nil
+}
+
+// Models returns new model instances, call it for every session.
+func (s Screen) Models() []model.Node {
+	nodes := make([]model.Node, 0, len(s))
+
+	for i, v := range s {
+		nodes = append(nodes, model.Node{
+			ID:    v.Key(i),
+			Model: v.Selection.Action(),
+			Links: v.Links(),
+			// styles are read only, shared by sessions
+			Styles: v.styles,
+			Roles:  v.Roles,
+		})
+	}
+
+	return nodes
+}
+
+// SetTheme resolves the theme of the views.
+func (s Screen) SetTheme(themes style.Themes) error {
+	var issues check.Issues
+
+	for i := range s {
+		theme, err := themes.Get(s[i].Theme)
+		if err != nil {
+			issues.Add(check.Join(check.Index("", i), "theme"), err)
+			continue
+		}
+
+		s[i].theme = theme
+		s[i].styles = theme.Styles(themes.Dark())
+	}
+
+	return issues.Err()
+}
+
+// Validate checks the views and the screen graph, all referenced IDs should
+// exist and all views should be reachable from the first view.
+func (s Screen) Validate() error {
+	if len(s) == 0 {
+		return check.Issues{{Message: ErrEmptyScreen.Error()}}
+	}
+
+	var issues check.Issues
+
+	ids := make(map[string]int, len(s))
+	for i, v := range s {
+		path := check.Index("", i)
+
+		if j, ok := ids[v.Key(i)]; ok {
+			issues.Addf(check.Join(path, "id"), "duplicated id %q with view %d", v.Key(i), j)
+			continue
+		}
+
+		ids[v.Key(i)] = i
+
+		issues.Add(check.Join(path, "selection"), v.Selection.Validate())
+	}
+
+	edges := make([][]int, len(s))
+	for i, v := range s {
+		path := check.Index("", i)
+
+		for _, dir := range []model.Direction{model.Next, model.Prev, model.Up, model.Down} {
+			id := v.Links()[dir]
+			if id == "" {
+				continue
+			}
+
+			j, ok := ids[id]
+			if !ok {
+				issues.Addf(check.Join(path, string(dir)), "unknown view %q", id)
+				continue
+			}
+
+			edges[i] = append(edges[i], j)
+		}
+
+		for _, id := range v.Selection.Targets() {
+			j, ok := ids[id]
+			if !ok {
+				issues.Addf(check.Join(path, "selection"), "unknown view %q", id)
+				continue
+			}
+
+			edges[i] = append(edges[i], j)
+		}
+
+		if v.Selection.Dynamic() {
+			for j := range s {
+				edges[i] = append(edges[i], j)
+			}
+		}
+
+		if v.Next == "" && i+1 < len(s) {
+			edges[i] = append(edges[i], i+1)
+		}
+	}
+
+	// walk from the first view
+	reached := make([]bool, len(s))
+	stack := []int{0}
+	reached[0] = true
+
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, j := range edges[i] {
+			if !reached[j] {
+				reached[j] = true
+				stack = append(stack, j)
+			}
+		}
+	}
 
-	for _, v := range s {
-		models = append(models, v.Selection.Action())
+	for i, ok := range reached {
+		if !ok {
+			issues.Addf(check.Index("", i), "view %q is unreachable from view %q", s[i].Key(i), s[0].Key(0))
+		}
 	}
 
-	return models
+	return issues.Err()
 }
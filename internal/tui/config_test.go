@@ -0,0 +1,149 @@
+package tui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rytsh/yap/internal/check"
+	"github.com/rytsh/yap/internal/tui/view/markdown"
+	"github.com/rytsh/yap/internal/tui/view/menu"
+)
+
+// page is a view with a document, links of the text are its targets.
+func page(id, text string) View {
+	return View{ID: id, Selection: Selection{Markdown: &markdown.Action{Text: text}}}
+}
+
+// menuView is a view with an entry for every target.
+func menuView(id string, targets ...string) View {
+	entries := make([]menu.Entry, 0, len(targets))
+	for _, target := range targets {
+		entries = append(entries, menu.Entry{Title: target, Target: target})
+	}
+
+	return View{ID: id, Selection: Selection{Menu: &menu.Action{Entries: entries}}}
+}
+
+func TestScreenValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		screen Screen
+		// want are the issues as "path: message"
+		want []string
+	}{
+		{
+			name: "empty",
+			want: []string{ErrEmptyScreen.Error()},
+		},
+		{
+			name:   "views in order",
+			screen: Screen{page("a", "a"), page("b", "b"), page("c", "c")},
+		},
+		{
+			name: "next breaks the order",
+			screen: Screen{
+				func() View { v := page("a", "a"); v.Next = "c"; return v }(),
+				page("b", "b"),
+				page("c", "c"),
+			},
+			want: []string{`[1]: view "b" is unreachable from view "a"`},
+		},
+		{
+			name: "up and down links",
+			screen: Screen{
+				func() View { v := page("a", "a"); v.Next = "a"; v.Down = "c"; return v }(),
+				func() View { v := page("b", "b"); v.Next = "b"; return v }(),
+				func() View { v := page("c", "c"); v.Up = "b"; return v }(),
+			},
+		},
+		{
+			name: "unknown links",
+			screen: Screen{
+				func() View { v := page("a", "a"); v.Prev = "x"; v.Up = "y"; return v }(),
+			},
+			want: []string{`[0].prev: unknown view "x"`, `[0].up: unknown view "y"`},
+		},
+		{
+			name: "menu targets",
+			screen: Screen{
+				func() View { v := menuView("menu", "c"); v.Next = "menu"; return v }(),
+				func() View { v := page("b", "b"); v.Next = "b"; return v }(),
+				page("c", "c"),
+			},
+			want: []string{`[1]: view "b" is unreachable from view "menu"`},
+		},
+		{
+			name: "markdown links",
+			screen: Screen{
+				func() View { v := page("doc", "see [c](view:c)"); v.Next = "doc"; return v }(),
+				func() View { v := page("b", "b"); v.Next = "b"; return v }(),
+				page("c", "c"),
+			},
+			want: []string{`[1]: view "b" is unreachable from view "doc"`},
+		},
+		{
+			name: "unknown targets",
+			screen: Screen{
+				menuView("menu", "x"),
+				page("doc", "see [y](view:y)"),
+			},
+			want: []string{`[0].selection: unknown view "x"`, `[1].selection: unknown view "y"`},
+		},
+		{
+			name: "dynamic target reaches every view",
+			screen: Screen{
+				menuView("menu", `{{ .values.view }}`),
+				func() View { v := page("b", "b"); v.Next = "b"; return v }(),
+				page("c", "c"),
+			},
+		},
+		{
+			name: "dynamic target of an unreachable view",
+			screen: Screen{
+				func() View { v := page("a", "a"); v.Next = "a"; return v }(),
+				menuView("menu", `{{ .values.view }}`),
+				page("c", "c"),
+			},
+			want: []string{
+				`[1]: view "menu" is unreachable from view "a"`,
+				`[2]: view "c" is unreachable from view "a"`,
+			},
+		},
+		{
+			name:   "duplicated ids",
+			screen: Screen{page("a", "a"), page("a", "b")},
+			want:   []string{`[1].id: duplicated id "a" with view 0`},
+		},
+		{
+			name:   "generated ids",
+			screen: Screen{{Selection: Selection{Markdown: &markdown.Action{Text: "[b](view:view-1)"}}}, page("", "b")},
+		},
+		{
+			name:   "invalid selection",
+			screen: Screen{page("a", "a"), {ID: "b"}},
+			want:   []string{"[1].selection: selection is empty"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+
+			if err := tt.screen.Validate(); err != nil {
+				issues, ok := err.(check.Issues)
+				if !ok {
+					t.Fatalf("Validate() error = %T, want issues", err)
+				}
+
+				for _, i := range issues {
+					got = append(got, i.Error())
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
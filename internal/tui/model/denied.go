@@ -0,0 +1,71 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rytsh/yap/internal/tui/style"
+)
+
+var ErrDenied = errors.New("access denied")
+
+// deniedModel is shown instead of a view without a role of the session, any
+// key goes back to the previous view, it quits without a previous view.
+type deniedModel struct {
+	width  int
+	height int
+
+	message string
+	back    tea.Model
+	styles  *style.Styles
+}
+
+func newDeniedModel(node Node, back tea.Model, styles *style.Styles, cfg Config) *deniedModel {
+	return &deniedModel{
+		width:   cfg.Width,
+		height:  cfg.Height,
+		message: fmt.Sprintf("Access denied: view %q needs one of the roles %s.", node.ID, strings.Join(node.Roles, ", ")),
+		back:    back,
+		styles:  styles,
+	}
+}
+
+func (m *deniedModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *deniedModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC || m.back == nil {
+			return m, tea.Quit
+		}
+
+		// the previous view gets the size it missed
+		return m.back.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	}
+
+	return m, nil
+}
+
+func (m *deniedModel) View() string {
+	help := "press any key to go back"
+	if m.back == nil {
+		help = "press any key to quit"
+	}
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center,
+		lipgloss.JoinVertical(lipgloss.Center,
+			m.styles.Error.Render(m.message),
+			"",
+			m.styles.Blurred.Render(help),
+		),
+	)
+}
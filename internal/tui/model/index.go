@@ -4,6 +4,10 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/metric"
+	"github.com/rytsh/yap/internal/tui/style"
 )
 
 type TimeMsg time.Time
@@ -14,57 +18,206 @@ type Model interface {
 	Initialize(Config) tea.Cmd
 }
 
+// Direction is a named neighbour of a view.
+type Direction string
+
+const (
+	Next Direction = "next"
+	Prev Direction = "prev"
+	Up   Direction = "up"
+	Down Direction = "down"
+)
+
 type Index interface {
 	InitModel(Config) (tea.Model, tea.Cmd)
 	PrevModel(Config) (tea.Model, tea.Cmd)
 	NextModel(Config) (tea.Model, tea.Cmd)
+	// Move goes to the named neighbour of the current view.
+	Move(Direction, Config) (tea.Model, tea.Cmd)
+	// Goto goes to the view with the ID.
+	Goto(string, Config) (tea.Model, tea.Cmd)
+	// Allowed returns true if the session has a role of the view with the ID.
+	Allowed(string) bool
 }
 
 type Config struct {
 	Width  int
 	Height int
+
+	Session *Session
+	// Styles is the theme of the view.
+	Styles *style.Styles
+}
+
+// Node is a view in the screen graph.
+type Node struct {
+	ID    string
+	Model Model
+	// Links are the neighbours, missing next follows the declaration order and
+	// missing prev goes back in the history.
+	Links map[Direction]string
+	// Styles is the theme of the view, default theme if nil.
+	Styles *style.Styles
+	// Roles are required to open the view, one of them is enough.
+	Roles []string
 }
 
 type IndexModel struct {
 	Width  int
 	Height int
 
-	Models     []Model
-	ModelIndex int
+	Session *Session
+
+	// Nodes should be new instances for every session.
+	Nodes []Node
+
+	// current is -1 before the first view.
+	current int
+	history []int
 }
 
 func (m *IndexModel) SetModels() tea.Model {
-	for i := range m.Models {
-		m.Models[i].SetIndex(m)
+	for i := range m.Nodes {
+		m.Nodes[i].Model.SetIndex(m)
 	}
 
-	return m.Models[0]
+	// Init of the model called by the program
+	next, _ := m.InitModel(Config{
+		Width:  m.Width,
+		Height: m.Height,
+	})
+
+	return next
+}
+
+func (m *IndexModel) find(id string) int {
+	for i := range m.Nodes {
+		if m.Nodes[i].ID == id {
+			return i
+		}
+	}
+
+	return -1
 }
 
 func (m *IndexModel) getModel(index int, cfg Config) (tea.Model, tea.Cmd) {
-	if index < 0 || index >= len(m.Models) {
-		return nil, nil
+	if index < 0 || index >= len(m.Nodes) {
+		// stay in the current view
+		return m.Nodes[m.current].Model, nil
+	}
+
+	m.current = index
+	metric.Transition(m.Nodes[index].ID)
+
+	if m.Session != nil {
+		m.Session.Audit(audit.Event{Type: audit.TypeNavigate, View: m.Nodes[index].ID})
 	}
 
-	return m.Models[index], m.Models[index].Initialize(cfg)
+	cfg.Session = m.Session
+	cfg.Styles = m.styles(index)
+
+	return m.Nodes[index].Model, m.Nodes[index].Model.Initialize(cfg)
 }
 
-func (m *IndexModel) InitModel(cfg Config) (tea.Model, tea.Cmd) {
-	m.ModelIndex = 0
+func (m *IndexModel) styles(index int) *style.Styles {
+	if m.Nodes[index].Styles == nil {
+		return style.DefaultStyles()
+	}
 
-	return m.getModel(m.ModelIndex, cfg)
+	return m.Nodes[index].Styles
 }
 
-func (m *IndexModel) PrevModel(cfg Config) (tea.Model, tea.Cmd) {
-	if m.ModelIndex != 0 {
-		m.ModelIndex--
+// visit records the current view to the history and goes to index, views
+// without a role of the session are denied.
+func (m *IndexModel) visit(index int, cfg Config) (tea.Model, tea.Cmd) {
+	if index >= 0 && index < len(m.Nodes) {
+		if !m.allowed(index) {
+			return m.deny(index, cfg)
+		}
+
+		if m.current >= 0 {
+			m.history = append(m.history, m.current)
+		}
+	}
+
+	return m.getModel(index, cfg)
+}
+
+func (m *IndexModel) allowed(index int) bool {
+	roles := m.Nodes[index].Roles
+	if len(roles) == 0 {
+		return true
 	}
 
-	return m.getModel(m.ModelIndex, cfg)
+	return m.Session != nil && m.Session.HasRole(roles)
+}
+
+// deny shows the access denied message, it goes back to the current view or
+// quits before the first view.
+func (m *IndexModel) deny(index int, cfg Config) (tea.Model, tea.Cmd) {
+	node := m.Nodes[index]
+
+	if m.Session != nil {
+		m.Session.Audit(audit.Event{
+			Type:   audit.TypeNavigate,
+			View:   node.ID,
+			Result: audit.ResultFailure,
+			Error:  ErrDenied.Error(),
+		})
+	}
+
+	if m.current < 0 {
+		return newDeniedModel(node, nil, m.styles(index), cfg), nil
+	}
+
+	return newDeniedModel(node, m.Nodes[m.current].Model, m.styles(m.current), cfg), nil
+}
+
+func (m *IndexModel) Allowed(id string) bool {
+	index := m.find(id)
+
+	return index >= 0 && m.allowed(index)
+}
+
+// InitModel goes to the first view with a new history, it is checked with
+// the roles of the session like the other views.
+func (m *IndexModel) InitModel(cfg Config) (tea.Model, tea.Cmd) {
+	m.current = -1
+	m.history = nil
+
+	return m.visit(0, cfg)
+}
+
+func (m *IndexModel) PrevModel(cfg Config) (tea.Model, tea.Cmd) {
+	return m.Move(Prev, cfg)
 }
 
 func (m *IndexModel) NextModel(cfg Config) (tea.Model, tea.Cmd) {
-	m.ModelIndex++
+	return m.Move(Next, cfg)
+}
+
+func (m *IndexModel) Move(dir Direction, cfg Config) (tea.Model, tea.Cmd) {
+	if id, ok := m.Nodes[m.current].Links[dir]; ok && id != "" {
+		return m.Goto(id, cfg)
+	}
+
+	switch dir {
+	case Next:
+		return m.visit(m.current+1, cfg)
+	case Prev:
+		if len(m.history) == 0 {
+			return m.getModel(-1, cfg)
+		}
+
+		index := m.history[len(m.history)-1]
+		m.history = m.history[:len(m.history)-1]
+
+		return m.getModel(index, cfg)
+	}
+
+	return m.getModel(-1, cfg)
+}
 
-	return m.getModel(m.ModelIndex, cfg)
+func (m *IndexModel) Goto(id string, cfg Config) (tea.Model, tea.Cmd) {
+	return m.visit(m.find(id), cfg)
 }
@@ -0,0 +1,182 @@
+package model
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakeModel is a view which counts its initializations.
+type fakeModel struct {
+	id    string
+	index Index
+	inits int
+}
+
+func (m *fakeModel) Init() tea.Cmd                       { return nil }
+func (m *fakeModel) Update(tea.Msg) (tea.Model, tea.Cmd) { return m, nil }
+func (m *fakeModel) View() string                        { return m.id }
+func (m *fakeModel) SetIndex(i Index)                    { m.index = i }
+
+func (m *fakeModel) Initialize(Config) tea.Cmd {
+	m.inits++
+
+	return nil
+}
+
+func newIndex(roles []string, nodes ...Node) *IndexModel {
+	session := NewSession("1", "alice", "127.0.0.1:2222")
+	session.SetRoles(roles)
+
+	for i := range nodes {
+		nodes[i].Model = &fakeModel{id: nodes[i].ID}
+	}
+
+	return &IndexModel{Session: session, Nodes: nodes}
+}
+
+func viewID(t *testing.T, m tea.Model) string {
+	t.Helper()
+
+	switch m := m.(type) {
+	case *fakeModel:
+		return m.id
+	case *deniedModel:
+		return "denied"
+	}
+
+	t.Fatalf("unknown model %T", m)
+
+	return ""
+}
+
+func TestIndexStart(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles []string
+		nodes []Node
+		want  string
+		// wantQuit is true if a key on the returned model quits
+		wantQuit bool
+	}{
+		{
+			name:  "first view",
+			nodes: []Node{{ID: "login"}, {ID: "menu", Roles: []string{"admin"}}},
+			want:  "login",
+		},
+		{
+			name:     "first view without a role is denied",
+			nodes:    []Node{{ID: "menu", Roles: []string{"admin"}}, {ID: "login"}},
+			want:     "denied",
+			wantQuit: true,
+		},
+		{
+			name:  "first view with a role",
+			roles: []string{"admin"},
+			nodes: []Node{{ID: "menu", Roles: []string{"admin"}}, {ID: "login"}},
+			want:  "menu",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newIndex(tt.roles, tt.nodes...)
+
+			first := m.SetModels()
+			if got := viewID(t, first); got != tt.want {
+				t.Fatalf("SetModels() = %s, want %s", got, tt.want)
+			}
+
+			if tt.wantQuit {
+				if _, cmd := first.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+					t.Error("key on the denied first view does not quit")
+				}
+
+				if m.Nodes[0].Model.(*fakeModel).inits != 0 {
+					t.Error("denied first view is initialized")
+				}
+
+				return
+			}
+
+			// the first view has no history
+			if next, _ := m.PrevModel(Config{}); next != first {
+				t.Errorf("PrevModel() = %s, want the first view", viewID(t, next))
+			}
+		})
+	}
+}
+
+func TestIndexInitModel(t *testing.T) {
+	m := newIndex([]string{"admin"},
+		Node{ID: "menu", Roles: []string{"admin"}},
+		Node{ID: "commands"},
+	)
+	m.SetModels()
+
+	if next, _ := m.NextModel(Config{}); viewID(t, next) != "commands" {
+		t.Fatalf("NextModel() = %s, want commands", viewID(t, next))
+	}
+
+	// logout clears the roles, the first view is checked again
+	m.Session.SetRoles(nil)
+
+	next, _ := m.InitModel(Config{})
+	if viewID(t, next) != "denied" {
+		t.Fatalf("InitModel() after logout = %s, want denied", viewID(t, next))
+	}
+
+	if _, cmd := next.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+		t.Error("key on the denied first view does not quit")
+	}
+
+	m.Session.SetRoles([]string{"admin"})
+
+	next, _ = m.InitModel(Config{})
+	if viewID(t, next) != "menu" {
+		t.Fatalf("InitModel() = %s, want menu", viewID(t, next))
+	}
+
+	if next, _ := m.PrevModel(Config{}); viewID(t, next) != "menu" {
+		t.Errorf("PrevModel() after InitModel = %s, want menu without history", viewID(t, next))
+	}
+}
+
+func TestIndexGoto(t *testing.T) {
+	m := newIndex([]string{"ops"},
+		Node{ID: "menu"},
+		Node{ID: "admin", Roles: []string{"admin"}},
+		Node{ID: "ops", Roles: []string{"ops", "admin"}},
+	)
+	m.SetModels()
+
+	tests := []struct {
+		name string
+		move func() (tea.Model, tea.Cmd)
+		want string
+	}{
+		{name: "denied", move: func() (tea.Model, tea.Cmd) { return m.Goto("admin", Config{}) }, want: "denied"},
+		{name: "allowed", move: func() (tea.Model, tea.Cmd) { return m.Goto("ops", Config{}) }, want: "ops"},
+		{name: "unknown view stays", move: func() (tea.Model, tea.Cmd) { return m.Goto("other", Config{}) }, want: "ops"},
+		{name: "back in the history", move: func() (tea.Model, tea.Cmd) { return m.PrevModel(Config{}) }, want: "menu"},
+		{name: "next is denied", move: func() (tea.Model, tea.Cmd) { return m.NextModel(Config{}) }, want: "denied"},
+	}
+
+	for _, tt := range tests {
+		next, _ := tt.move()
+		if got := viewID(t, next); got != tt.want {
+			t.Errorf("%s: view = %s, want %s", tt.name, got, tt.want)
+		}
+
+		// denied view goes back to the current view
+		if d, ok := next.(*deniedModel); ok {
+			if back, _ := d.Update(tea.KeyMsg{Type: tea.KeyEnter}); back != m.Nodes[m.current].Model {
+				t.Errorf("%s: denied view goes back to %s", tt.name, viewID(t, back))
+			}
+		}
+	}
+
+	if m.Allowed("admin") || !m.Allowed("ops") || !m.Allowed("menu") || m.Allowed("other") {
+		t.Error("Allowed() does not match the roles")
+	}
+}
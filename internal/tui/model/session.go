@@ -0,0 +1,245 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/hold"
+)
+
+// Authentication methods of the session identity.
+const (
+	MethodPublicKey = "publickey"
+	MethodPassword  = "password"
+	MethodOAuth2    = "oauth2"
+)
+
+// Session is the context of one connection, shared by all models of it.
+type Session struct {
+	ID string
+	// User is the SSH user name.
+	User       string
+	RemoteAddr string
+	Start      time.Time
+	// Cache keeps the runbook progress of the users.
+	Cache hold.Cache
+	// Fingerprint is the SHA256 fingerprint of the accepted public key.
+	Fingerprint string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex    sync.RWMutex
+	identity string
+	method   string
+	tab      string
+	roles    []string
+	form     map[string]interface{}
+	// secrets are the names of the password values of the form.
+	secrets map[string]bool
+	values  map[string]interface{}
+}
+
+func NewSession(id, user, remoteAddr string) *Session {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Session{
+		ID:         id,
+		User:       user,
+		RemoteAddr: remoteAddr,
+		Start:      time.Now(),
+		ctx:        ctx,
+		cancel:     cancel,
+		form:       make(map[string]interface{}),
+		secrets:    make(map[string]bool),
+		values:     make(map[string]interface{}),
+	}
+}
+
+// Context is canceled when the session is closed, processes started by the
+// models should stop with it.
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// Close cancels the context, it is called when the program exits.
+func (s *Session) Close() {
+	s.cancel()
+}
+
+// Identity returns the authenticated user, empty if not authenticated.
+func (s *Session) Identity() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.identity
+}
+
+// Method returns the authentication method of the identity.
+func (s *Session) Method() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.method
+}
+
+func (s *Session) SetIdentity(identity, method string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.identity = identity
+	s.method = method
+}
+
+// Tab returns the login tab of the identity, empty for the SSH layer identities.
+func (s *Session) Tab() string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.tab
+}
+
+func (s *Session) SetTab(tab string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tab = tab
+}
+
+// Roles returns the roles of the identity from the auth provider.
+func (s *Session) Roles() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]string(nil), s.roles...)
+}
+
+func (s *Session) SetRoles(roles []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.roles = append([]string(nil), roles...)
+}
+
+// HasRole returns true if the identity has one of the roles, no roles are
+// required if empty. Roles are case sensitive.
+func (s *Session) HasRole(roles []string) bool {
+	if len(roles) == 0 {
+		return true
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, r := range roles {
+		for _, v := range s.roles {
+			if r == v {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Form returns the submitted form values by field name.
+func (s *Session) Form() map[string]interface{} {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	v := make(map[string]interface{}, len(s.form))
+	for k, value := range s.form {
+		v[k] = value
+	}
+
+	return v
+}
+
+// SetForm adds the values of a submitted form, values of the same names are
+// replaced. Secrets are the names of the password values.
+func (s *Session) SetForm(values map[string]interface{}, secrets ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for k, v := range values {
+		s.form[k] = v
+		delete(s.secrets, k)
+	}
+
+	for _, k := range secrets {
+		s.secrets[k] = true
+	}
+}
+
+// FormatValue returns the form value as a string, lists are comma separated.
+func FormatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []string:
+		return strings.Join(v, ",")
+	}
+
+	return fmt.Sprint(v)
+}
+
+// Logout drops the identity, the roles and the form values.
+func (s *Session) Logout() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.identity = ""
+	s.method = ""
+	s.tab = ""
+	s.roles = nil
+	s.form = make(map[string]interface{})
+}
+
+// Audit logs the event with the session information.
+func (s *Session) Audit(e audit.Event) {
+	e.SessionID = s.ID
+	e.RemoteAddr = s.RemoteAddr
+	e.User = s.User
+
+	if e.Identity == "" {
+		e.Identity = s.Identity()
+	}
+
+	if e.Method == "" {
+		e.Method = s.Method()
+	}
+
+	audit.Log(e)
+}
+
+func (s *Session) Get(key string) (interface{}, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	v, ok := s.values[key]
+
+	return v, ok
+}
+
+func (s *Session) Set(key string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.values[key] = value
+}
+
+func (s *Session) Delete(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.values, key)
+}
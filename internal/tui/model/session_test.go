@@ -0,0 +1,30 @@
+package model
+
+import "testing"
+
+func TestSessionHasRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		roles    []string
+		required []string
+		want     bool
+	}{
+		{name: "no roles required", want: true},
+		{name: "no roles required with roles", roles: []string{"admins"}, want: true},
+		{name: "one of the roles", roles: []string{"ops", "admins"}, required: []string{"users", "admins"}, want: true},
+		{name: "missing role", roles: []string{"ops"}, required: []string{"admins"}},
+		{name: "without roles", required: []string{"admins"}},
+		{name: "case sensitive", roles: []string{"Admins"}, required: []string{"admins"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession("1", "alice", "127.0.0.1:2222")
+			s.SetRoles(tt.roles)
+
+			if got := s.HasRole(tt.required); got != tt.want {
+				t.Errorf("HasRole(%q) = %v, want %v", tt.required, got, tt.want)
+			}
+		})
+	}
+}
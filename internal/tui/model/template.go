@@ -0,0 +1,157 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// TemplateData is the data of the action templates.
+type TemplateData struct {
+	SessionID string
+	// User is the SSH user name.
+	User       string
+	RemoteAddr string
+	// Identity is the authenticated user.
+	Identity string
+	Method   string
+	Roles    []string
+	// Tab is the login tab of the identity.
+	Tab string
+	// Form values are strings, lists are comma separated.
+	Form map[string]string
+	// Env is the environment of the server.
+	Env map[string]string
+}
+
+// TemplateData returns the current values of the session.
+func (s *Session) TemplateData() TemplateData {
+	form := s.Form()
+
+	data := TemplateData{
+		SessionID:  s.ID,
+		User:       s.User,
+		RemoteAddr: s.RemoteAddr,
+		Identity:   s.Identity(),
+		Method:     s.Method(),
+		Roles:      s.Roles(),
+		Tab:        s.Tab(),
+		Form:       make(map[string]string, len(form)),
+		Env:        make(map[string]string),
+	}
+
+	for k, v := range form {
+		data.Form[k] = FormatValue(v)
+	}
+
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			data.Env[k] = v
+		}
+	}
+
+	return data
+}
+
+// MaskedTemplateData returns the values of the session with the password
+// values of the forms as "***", used for the values shown and logged.
+func (s *Session) MaskedTemplateData() TemplateData {
+	data := s.TemplateData()
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for k := range s.secrets {
+		if _, ok := data.Form[k]; ok {
+			data.Form[k] = "***"
+		}
+	}
+
+	return data
+}
+
+var templateFuncs = template.FuncMap{
+	"shquote": ShellQuote,
+	"shjoin":  shellJoin,
+	"split":   strings.Split,
+	"join":    strings.Join,
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"trim":    strings.TrimSpace,
+	"default": func(def, v string) string {
+		if v == "" {
+			return def
+		}
+
+		return v
+	},
+}
+
+// IsTemplate returns true if the text has template actions.
+func IsTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+func parseTemplate(text string, strict bool) (*template.Template, error) {
+	tpl := template.New("").Funcs(templateFuncs)
+	if strict {
+		tpl = tpl.Option("missingkey=error")
+	} else {
+		tpl = tpl.Option("missingkey=zero")
+	}
+
+	return tpl.Parse(text) //nolint:wrapcheck // no need
+}
+
+// CheckTemplate returns the parse error of the text.
+func CheckTemplate(text string) error {
+	if !IsTemplate(text) {
+		return nil
+	}
+
+	if _, err := parseTemplate(text, false); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+
+	return nil
+}
+
+// Render executes the text with the data, strict mode fails on missing keys.
+func Render(text string, data TemplateData, strict bool) (string, error) {
+	if !IsTemplate(text) {
+		return text, nil
+	}
+
+	tpl, err := parseTemplate(text, strict)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	var b strings.Builder
+	if err := tpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("template %q: %w", text, err)
+	}
+
+	return b.String(), nil
+}
+
+// ShellQuote returns the value in single quotes for POSIX shells.
+func ShellQuote(v interface{}) string {
+	s := FormatValue(v)
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-.,:/@%+=") == "" {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellJoin quotes the values and joins them with spaces.
+func shellJoin(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = ShellQuote(v)
+	}
+
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data := TemplateData{
+		User:     "alice",
+		Identity: "alice@example.com",
+		Roles:    []string{"admin", "ops"},
+		Form:     map[string]string{"host": "web-1", "hosts": "web-1,web 2", "empty": ""},
+		Env:      map[string]string{"HOME": "/home/alice"},
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		strict  bool
+		want    string
+		wantErr bool
+	}{
+		{name: "plain text", text: "uptime", want: "uptime"},
+		{name: "plain text is not parsed", text: "echo {x}", strict: true, want: "echo {x}"},
+		{name: "form value", text: "ssh {{ .Form.host }}", want: "ssh web-1"},
+		{name: "missing key", text: "ssh {{ .Form.port }}", want: "ssh "},
+		{name: "missing key in strict mode", text: "ssh {{ .Form.port }}", strict: true, wantErr: true},
+		{name: "empty value in strict mode", text: "[{{ .Form.empty }}]", strict: true, want: "[]"},
+		{name: "unknown field", text: "{{ .Password }}", wantErr: true},
+		{name: "default", text: `{{ default "22" .Form.port }}`, want: "22"},
+		{name: "env", text: "{{ .Env.HOME }}", strict: true, want: "/home/alice"},
+		{name: "roles", text: `{{ join .Roles "," }}`, want: "admin,ops"},
+		{name: "shquote", text: "echo {{ shquote .Form.hosts }}", want: "echo 'web-1,web 2'"},
+		{name: "shjoin", text: `ping {{ shjoin (split .Form.hosts ",") }}`, want: "ping web-1 'web 2'"},
+		{name: "case", text: "{{ upper .User }} {{ lower .Identity }}", want: "ALICE alice@example.com"},
+		{name: "parse error", text: "{{ .Form.host ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.text, data, tt.strict)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	tests := []struct {
+		text    string
+		wantErr bool
+	}{
+		{text: ""},
+		{text: "{ not a template }"},
+		{text: "{{ .Form.host }}"},
+		{text: "{{ unknown .Form.host }}", wantErr: true},
+		{text: "{{ if .Form.host }}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := CheckTemplate(tt.text); (err != nil) != tt.wantErr {
+			t.Errorf("CheckTemplate(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{value: "web-1", want: "web-1"},
+		{value: "user@host:/path/file.txt", want: "user@host:/path/file.txt"},
+		{value: "", want: "''"},
+		{value: nil, want: "''"},
+		{value: "two words", want: "'two words'"},
+		{value: "it's", want: `'it'\''s'`},
+		{value: "$(reboot)", want: "'$(reboot)'"},
+		{value: "a;b|c&d", want: "'a;b|c&d'"},
+		{value: "*", want: "'*'"},
+		{value: "~", want: "'~'"},
+		{value: "line\nbreak", want: "'line\nbreak'"},
+		{value: 1.5, want: "1.5"},
+		{value: []string{"a", "b c"}, want: "'a,b c'"},
+	}
+
+	for _, tt := range tests {
+		if got := ShellQuote(tt.value); got != tt.want {
+			t.Errorf("ShellQuote(%#v) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuoteShell(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not found")
+	}
+
+	for _, v := range []string{"it's", `"$HOME" \ $(id) ; *`, "a\nb", "'''", "-n"} {
+		out, err := exec.Command(sh, "-c", "printf '%s' "+ShellQuote(v)).Output()
+		if err != nil {
+			t.Fatalf("sh error = %v", err)
+		}
+
+		if string(out) != v {
+			t.Errorf("sh got %q, want %q", out, v)
+		}
+	}
+}
+
+func TestMaskedTemplateData(t *testing.T) {
+	s := NewSession("1", "alice", "127.0.0.1:2222")
+	s.SetForm(map[string]interface{}{"host": "web-1", "password": "secret"}, "password", "token")
+
+	text := "deploy {{ .Form.host }} {{ .Form.password }} {{ .Form.token }}"
+
+	got, err := Render(text, s.MaskedTemplateData(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "deploy web-1 *** "; got != want {
+		t.Errorf("Render() masked = %q, want %q", got, want)
+	}
+
+	if got, _ := Render(text, s.TemplateData(), false); got != "deploy web-1 secret " {
+		t.Errorf("Render() = %q, want the password", got)
+	}
+
+	// value of another form with the same name is not a secret anymore
+	s.SetForm(map[string]interface{}{"password": "visible"})
+
+	if got, _ := Render(text, s.MaskedTemplateData(), false); got != "deploy web-1 visible " {
+		t.Errorf("Render() masked after a plain value = %q", got)
+	}
+}
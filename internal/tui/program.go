@@ -0,0 +1,27 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rytsh/yap/internal/tui/model"
+)
+
+// Start returns the first model of the screen, models are created for the session.
+func (s Screen) Start(session *model.Session, width, height int) tea.Model {
+	m := &model.IndexModel{
+		Width:  width,
+		Height: height,
+
+		Session: session,
+
+		Nodes: s.Models(),
+	}
+
+	return m.SetModels()
+}
+
+// NewProgram returns a program which gets time messages, the session is
+// closed after the timeouts.
+func NewProgram(session *model.Session, m tea.Model, timeout Timeout, opts ...tea.ProgramOption) *tea.Program {
+	return tea.NewProgram(newSessionModel(session, m, timeout), opts...)
+}
@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Store holds the screen of the new sessions, started sessions keep their own snapshot.
+type Store struct {
+	screen atomic.Pointer[Screen]
+}
+
+func NewStore(s Screen) *Store {
+	store := &Store{}
+	store.screen.Store(&s)
+
+	return store
+}
+
+// Load returns the current screen.
+func (s *Store) Load() Screen {
+	return *s.screen.Load()
+}
+
+// Swap replaces the screen and returns the old one.
+func (s *Store) Swap(screen Screen) Screen {
+	return *s.screen.Swap(&screen)
+}
+
+// Diff describes the changed views between two screens, empty if nothing changed.
+func Diff(old, new Screen) string {
+	oldViews := make(map[string][]byte, len(old))
+	for i, v := range old {
+		oldViews[v.Key(i)] = marshalView(v)
+	}
+
+	var added, changed, removed []string
+
+	newKeys := make(map[string]struct{}, len(new))
+	for i, v := range new {
+		key := v.Key(i)
+		newKeys[key] = struct{}{}
+
+		prev, ok := oldViews[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case !bytes.Equal(prev, marshalView(v)):
+			changed = append(changed, key)
+		}
+	}
+
+	for i, v := range old {
+		if _, ok := newKeys[v.Key(i)]; !ok {
+			removed = append(removed, v.Key(i))
+		}
+	}
+
+	var parts []string
+	if len(added) > 0 {
+		parts = append(parts, fmt.Sprintf("added [%s]", strings.Join(added, ", ")))
+	}
+
+	if len(changed) > 0 {
+		parts = append(parts, fmt.Sprintf("changed [%s]", strings.Join(changed, ", ")))
+	}
+
+	if len(removed) > 0 {
+		parts = append(parts, fmt.Sprintf("removed [%s]", strings.Join(removed, ", ")))
+	}
+
+	if len(parts) == 0 {
+		for i := range old {
+			if old[i].Key(i) != new[i].Key(i) {
+				return "views reordered"
+			}
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func marshalView(v View) []byte {
+	b, err := json.Marshal([]interface{}{v, v.theme})
+	if err != nil {
+		// not comparable, count as changed
+		return []byte(err.Error())
+	}
+
+	return b
+}
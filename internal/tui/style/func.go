@@ -2,35 +2,37 @@ package style
 
 import (
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/lucasb-eyer/go-colorful"
 )
 
-func Tabs(tabs []string, selected string, width int) string {
+// Tabs renders the tab row with the width.
+func (s *Styles) Tabs(tabs []string, selected string, width int) string {
 	var renderTabs []string
 	for i, tab := range tabs {
 		if tab == selected {
 			if i == 0 {
-				renderTabs = append(renderTabs, TabActiveFirst.Render(tab))
+				renderTabs = append(renderTabs, s.Tab.Copy().Border(BorderActiveTabFirst, true).Render(tab))
 			} else if i == len(tabs)-1 {
-				renderTabs = append(renderTabs, TabActiveLast.Render(tab))
+				renderTabs = append(renderTabs, s.Tab.Copy().Border(BorderActiveTabLast, true).Render(tab))
 			} else {
-				renderTabs = append(renderTabs, TabActive.Render(tab))
+				renderTabs = append(renderTabs, s.Tab.Copy().Border(BorderActiveTab, true).Render(tab))
 			}
 		} else {
 			if i == 0 {
-				renderTabs = append(renderTabs, TabNormalFirst.Render(tab))
+				renderTabs = append(renderTabs, s.Tab.Copy().Border(BorderNormalTabFirst, true).Render(tab))
 			} else if i == len(tabs)-1 {
-				renderTabs = append(renderTabs, TabNormalLast.Render(tab))
+				renderTabs = append(renderTabs, s.Tab.Copy().Border(BorderNormalTabLast, true).Render(tab))
 			} else {
-				renderTabs = append(renderTabs, TabNormal.Render(tab))
+				renderTabs = append(renderTabs, s.Tab.Copy().Border(BorderNormalTab, true).Render(tab))
 			}
 		}
 	}
 
 	if len(renderTabs) == 0 {
-		renderTabs = append(renderTabs, Tab.Render("nothing"))
+		renderTabs = append(renderTabs, s.Tab.Render("nothing"))
 	}
 
 	row := lipgloss.JoinHorizontal(
@@ -38,7 +40,7 @@ func Tabs(tabs []string, selected string, width int) string {
 		renderTabs...,
 	)
 
-	gap := TabGap.Render(strings.Repeat(" ", Max(0, width-lipgloss.Width(row)-1)))
+	gap := s.TabGap.Render(strings.Repeat(" ", Max(0, width-lipgloss.Width(row)-1)))
 	return lipgloss.JoinHorizontal(lipgloss.Bottom, row, gap)
 }
 
@@ -98,9 +100,25 @@ func ColorGrid(xSteps, ySteps int) [][]string {
 	return grid
 }
 
+// Countdown returns the remaining time in seconds, at least one second.
+func Countdown(d time.Duration) time.Duration {
+	if d < time.Second {
+		return time.Second
+	}
+
+	return d.Round(time.Second)
+}
+
 func Max(a, b int) int {
 	if a > b {
 		return a
 	}
 	return b
 }
+
+func Min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
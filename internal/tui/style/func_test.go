@@ -0,0 +1,26 @@
+package style
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCountdown(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want time.Duration
+	}{
+		{d: -time.Second, want: time.Second},
+		{d: 0, want: time.Second},
+		{d: 200 * time.Millisecond, want: time.Second},
+		{d: 1400 * time.Millisecond, want: time.Second},
+		{d: 1500 * time.Millisecond, want: 2 * time.Second},
+		{d: time.Minute + 10*time.Millisecond, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := Countdown(tt.d); got != tt.want {
+			t.Errorf("Countdown(%s) = %s, want %s", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package style
+
+import (
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/ansi"
+)
+
+// markdown returns the glamour style with the colors of the theme, the
+// builtin dark or light style is the base.
+func (t Theme) markdown(dark bool) ansi.StyleConfig {
+	v := glamour.LightStyleConfig
+	if dark {
+		v = glamour.DarkStyleConfig
+	}
+
+	color := func(c Color) *string {
+		if c == "" {
+			return nil
+		}
+
+		s := c.resolve(dark)
+
+		return &s
+	}
+
+	v.Heading.Color = color(t.Palette.Highlight)
+	v.H1.Color = color(t.ActiveButton.Foreground)
+	v.H1.BackgroundColor = color(t.ActiveButton.Background)
+	v.H6.Color = color(t.Palette.Blur)
+	v.BlockQuote.Color = color(t.Palette.Blur)
+	v.HorizontalRule.Color = color(t.Palette.Subtle)
+	v.Link.Color = color(t.Palette.Special)
+	v.LinkText.Color = color(t.Palette.Focus)
+	v.Code.Color = color(t.Palette.Special)
+
+	return v
+}
@@ -0,0 +1,61 @@
+package style
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"rsc.io/qr"
+)
+
+// quietZone is the white border around the QR code in modules.
+const quietZone = 2
+
+var (
+	qrBlack = lipgloss.Color("#000000")
+	qrWhite = lipgloss.Color("#FFFFFF")
+)
+
+// QRCode renders content as a QR code with half blocks, two modules in a cell.
+func QRCode(content string) (string, error) {
+	code, err := qr.Encode(content, qr.L)
+	if err != nil {
+		return "", fmt.Errorf("qr encode: %w", err)
+	}
+
+	black := func(x, y int) bool {
+		x, y = x-quietZone, y-quietZone
+		if x < 0 || y < 0 || x >= code.Size || y >= code.Size {
+			return false
+		}
+
+		return code.Black(x, y)
+	}
+
+	color := func(isBlack bool) lipgloss.Color {
+		if isBlack {
+			return qrBlack
+		}
+
+		return qrWhite
+	}
+
+	cell := lipgloss.NewStyle()
+	size := code.Size + 2*quietZone
+
+	var b strings.Builder
+	for y := 0; y < size; y += 2 {
+		for x := 0; x < size; x++ {
+			b.WriteString(cell.
+				Foreground(color(black(x, y))).
+				Background(color(black(x, y+1))).
+				Render("▀"))
+		}
+
+		if y+2 < size {
+			b.WriteRune('\n')
+		}
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,393 @@
+package style
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rytsh/yap/internal/check"
+)
+
+// DefaultTheme is the name of the theme used when no theme is selected.
+const DefaultTheme = "default"
+
+// Backgrounds of the client terminals.
+const (
+	BackgroundDark  = "dark"
+	BackgroundLight = "light"
+)
+
+var reHexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Themes selects the themes of the views.
+type Themes struct {
+	// Name is the theme of the views without a theme.
+	Name string `cfg:"name"`
+	// Custom are named themes, they extend their base theme.
+	Custom map[string]Theme `cfg:"custom"`
+	// Background of the clients selects the adaptive colors, dark or light.
+	// Dark if empty, the local command detects it from the terminal.
+	Background string `cfg:"background"`
+}
+
+// Dark returns true if the adaptive colors are for a dark background.
+func (t Themes) Dark() bool {
+	return t.Background != BackgroundLight
+}
+
+// Theme is the look of the views, empty values are taken from the base theme.
+type Theme struct {
+	// Base is a built-in theme name, default if empty.
+	Base    string  `cfg:"base"`
+	Palette Palette `cfg:"palette"`
+	// Border kind of the boxes; normal, rounded, thick, double, hidden.
+	Border string `cfg:"border"`
+	// BannerBorder is the border kind of the banners.
+	BannerBorder string `cfg:"banner_border"`
+	// Whitespace fills the space around the login box.
+	Whitespace string `cfg:"whitespace"`
+
+	Button       Style `cfg:"button"`
+	ActiveButton Style `cfg:"active_button"`
+	Tab          Style `cfg:"tab"`
+	Error        Style `cfg:"error"`
+}
+
+type Palette struct {
+	Subtle    Color `cfg:"subtle"`
+	Highlight Color `cfg:"highlight"`
+	Special   Color `cfg:"special"`
+	Focus     Color `cfg:"focus"`
+	Blur      Color `cfg:"blur"`
+	Fail      Color `cfg:"fail"`
+}
+
+type Style struct {
+	Foreground Color `cfg:"foreground"`
+	Background Color `cfg:"background"`
+	// Border is the border color if the element has a border.
+	Border    Color `cfg:"border"`
+	Bold      bool  `cfg:"bold"`
+	Italic    bool  `cfg:"italic"`
+	Underline bool  `cfg:"underline"`
+}
+
+// Color is a hex color like "#874BFD", an ANSI color number or an adaptive
+// color as "light,dark".
+type Color string
+
+var builtinThemes = map[string]Theme{
+	DefaultTheme: {
+		Palette: Palette{
+			Subtle:    "#D9DCCF,#383838",
+			Highlight: "#874BFD,#7D56F4",
+			Special:   "#43BF6D,#73F59F",
+			Focus:     "205",
+			Blur:      "240",
+			Fail:      "#FF5F87",
+		},
+		Border:       "normal",
+		BannerBorder: "double",
+		Whitespace:   "  ##  ",
+		Button:       Style{Foreground: "#FFF7DB", Background: "#888B7E"},
+		ActiveButton: Style{Foreground: "#FFF7DB", Background: "#F25D94", Underline: true},
+		Error:        Style{Foreground: "0", Background: "3"},
+	},
+	"mono": {
+		Palette: Palette{
+			Subtle:    "250,238",
+			Highlight: "240,250",
+			Special:   "0,15",
+			Focus:     "0,15",
+			Blur:      "244",
+			Fail:      "244",
+		},
+		Border:       "normal",
+		BannerBorder: "normal",
+		Whitespace:   " ",
+		Button:       Style{Foreground: "0", Background: "250"},
+		ActiveButton: Style{Foreground: "15", Background: "240", Bold: true, Underline: true},
+		Error:        Style{Foreground: "15", Background: "238", Bold: true},
+	},
+	"ocean": {
+		Palette: Palette{
+			Subtle:    "#CFE3EC,#1E3A4C",
+			Highlight: "#0277BD,#4FC3F7",
+			Special:   "#00897B,#4DB6AC",
+			Focus:     "#0288D1,#81D4FA",
+			Blur:      "#78909C",
+			Fail:      "#E57373",
+		},
+		Border:       "rounded",
+		BannerBorder: "rounded",
+		Whitespace:   " ~  ",
+		Button:       Style{Foreground: "#E1F5FE", Background: "#546E7A"},
+		ActiveButton: Style{Foreground: "#E1F5FE", Background: "#0288D1", Bold: true},
+		Error:        Style{Foreground: "#FFFFFF", Background: "#C62828"},
+	},
+}
+
+// ThemeNames returns the built-in theme names.
+func ThemeNames() []string {
+	names := make([]string, 0, len(builtinThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Get returns the resolved theme, the global theme is used for an empty name.
+func (t Themes) Get(name string) (Theme, error) {
+	if name == "" {
+		name = t.Name
+	}
+
+	if name == "" {
+		name = DefaultTheme
+	}
+
+	if custom, ok := t.Custom[name]; ok {
+		base, ok := builtinThemes[custom.baseName()]
+		if !ok {
+			return Theme{}, fmt.Errorf("theme %q: unknown base theme %q", name, custom.Base)
+		}
+
+		return custom.merge(base), nil
+	}
+
+	if theme, ok := builtinThemes[name]; ok {
+		return theme, nil
+	}
+
+	return Theme{}, fmt.Errorf("unknown theme %q, built-in themes are %s", name, strings.Join(ThemeNames(), ", "))
+}
+
+// Validate checks the custom themes and the selected theme.
+func (t Themes) Validate() error {
+	var issues check.Issues
+
+	names := make([]string, 0, len(t.Custom))
+	for name := range t.Custom {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		theme := t.Custom[name]
+		path := check.Join("custom", name)
+
+		if _, ok := builtinThemes[theme.baseName()]; !ok {
+			issues.Addf(check.Join(path, "base"), "unknown base theme %q", theme.Base)
+		}
+
+		issues.Add(path, theme.Validate())
+	}
+
+	if t.Name != "" && !t.exists(t.Name) {
+		issues.Addf("name", "unknown theme %q", t.Name)
+	}
+
+	switch t.Background {
+	case "", BackgroundDark, BackgroundLight:
+	default:
+		issues.Addf("background", "unknown background %q, use dark or light", t.Background)
+	}
+
+	return issues.Err()
+}
+
+func (t Themes) exists(name string) bool {
+	if _, ok := t.Custom[name]; ok {
+		return true
+	}
+
+	_, ok := builtinThemes[name]
+
+	return ok
+}
+
+// Validate checks the colors and the border kinds.
+func (t Theme) Validate() error {
+	var issues check.Issues
+
+	colors := map[string]Color{
+		"palette.subtle":    t.Palette.Subtle,
+		"palette.highlight": t.Palette.Highlight,
+		"palette.special":   t.Palette.Special,
+		"palette.focus":     t.Palette.Focus,
+		"palette.blur":      t.Palette.Blur,
+		"palette.fail":      t.Palette.Fail,
+	}
+
+	for path, s := range map[string]Style{
+		"button":        t.Button,
+		"active_button": t.ActiveButton,
+		"tab":           t.Tab,
+		"error":         t.Error,
+	} {
+		colors[path+".foreground"] = s.Foreground
+		colors[path+".background"] = s.Background
+		colors[path+".border"] = s.Border
+	}
+
+	for path, c := range colors {
+		issues.Add(path, c.Validate())
+	}
+
+	if _, err := borderKind(t.Border); err != nil {
+		issues.Add("border", err)
+	}
+
+	if _, err := borderKind(t.BannerBorder); err != nil {
+		issues.Add("banner_border", err)
+	}
+
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Path < issues[j].Path })
+
+	return issues.Err()
+}
+
+func (t Theme) baseName() string {
+	if t.Base == "" {
+		return DefaultTheme
+	}
+
+	return t.Base
+}
+
+// merge returns the theme with empty values from the base.
+func (t Theme) merge(base Theme) Theme {
+	base.Palette = Palette{
+		Subtle:    t.Palette.Subtle.or(base.Palette.Subtle),
+		Highlight: t.Palette.Highlight.or(base.Palette.Highlight),
+		Special:   t.Palette.Special.or(base.Palette.Special),
+		Focus:     t.Palette.Focus.or(base.Palette.Focus),
+		Blur:      t.Palette.Blur.or(base.Palette.Blur),
+		Fail:      t.Palette.Fail.or(base.Palette.Fail),
+	}
+
+	if t.Border != "" {
+		base.Border = t.Border
+	}
+
+	if t.BannerBorder != "" {
+		base.BannerBorder = t.BannerBorder
+	}
+
+	if t.Whitespace != "" {
+		base.Whitespace = t.Whitespace
+	}
+
+	base.Button = t.Button.merge(base.Button)
+	base.ActiveButton = t.ActiveButton.merge(base.ActiveButton)
+	base.Tab = t.Tab.merge(base.Tab)
+	base.Error = t.Error.merge(base.Error)
+
+	return base
+}
+
+func (s Style) merge(base Style) Style {
+	return Style{
+		Foreground: s.Foreground.or(base.Foreground),
+		Background: s.Background.or(base.Background),
+		Border:     s.Border.or(base.Border),
+		Bold:       s.Bold || base.Bold,
+		Italic:     s.Italic || base.Italic,
+		Underline:  s.Underline || base.Underline,
+	}
+}
+
+// lipgloss returns the style with the colors, border color is not set.
+func (s Style) lipgloss(dark bool) lipgloss.Style {
+	v := lipgloss.NewStyle().Bold(s.Bold).Italic(s.Italic).Underline(s.Underline)
+
+	if s.Foreground != "" {
+		v = v.Foreground(s.Foreground.Terminal(dark))
+	}
+
+	if s.Background != "" {
+		v = v.Background(s.Background.Terminal(dark))
+	}
+
+	return v
+}
+
+func (c Color) or(v Color) Color {
+	if c == "" {
+		return v
+	}
+
+	return c
+}
+
+// Validate checks the color format, empty color is valid.
+func (c Color) Validate() error {
+	if c == "" {
+		return nil
+	}
+
+	parts := strings.Split(string(c), ",")
+	if len(parts) > 2 {
+		return fmt.Errorf("invalid color %q, use a single color or light,dark", c)
+	}
+
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if reHexColor.MatchString(p) {
+			continue
+		}
+
+		if n, err := strconv.Atoi(p); err == nil && n >= 0 && n <= 255 {
+			continue
+		}
+
+		return fmt.Errorf("invalid color %q, use a hex color or an ANSI color number", p)
+	}
+
+	return nil
+}
+
+// Terminal returns the lipgloss color for the background, adaptive colors are
+// resolved here so the terminal is not queried.
+func (c Color) Terminal(dark bool) lipgloss.TerminalColor {
+	return lipgloss.Color(c.resolve(dark))
+}
+
+// resolve returns the color for the background, dark one of the adaptive colors.
+func (c Color) resolve(dark bool) string {
+	light, darkColor, ok := strings.Cut(string(c), ",")
+	if !ok {
+		return strings.TrimSpace(string(c))
+	}
+
+	if dark {
+		return strings.TrimSpace(darkColor)
+	}
+
+	return strings.TrimSpace(light)
+}
+
+func borderKind(kind string) (lipgloss.Border, error) {
+	switch kind {
+	case "", "normal":
+		return lipgloss.NormalBorder(), nil
+	case "rounded":
+		return lipgloss.RoundedBorder(), nil
+	case "thick":
+		return lipgloss.ThickBorder(), nil
+	case "double":
+		return lipgloss.DoubleBorder(), nil
+	case "hidden":
+		return lipgloss.HiddenBorder(), nil
+	}
+
+	return lipgloss.Border{}, fmt.Errorf("unknown border %q, use normal, rounded, thick, double or hidden", kind)
+}
@@ -0,0 +1,208 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/check"
+	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/style"
+)
+
+var (
+	ErrIdleTimeout    = errors.New("session closed after idle timeout")
+	ErrSessionExpired = errors.New("session reached the maximum duration")
+)
+
+// Timeout closes the forgotten sessions, zero durations disable the limits.
+type Timeout struct {
+	// Idle is the allowed time without key input.
+	Idle time.Duration `cfg:"idle"`
+	// Warning is the countdown shown before the session is closed.
+	Warning time.Duration `cfg:"warning"`
+	// Max is the lifetime of the session.
+	Max time.Duration `cfg:"max"`
+}
+
+func (t Timeout) Validate() error {
+	var issues check.Issues
+
+	if t.Idle < 0 {
+		issues.Addf("idle", "idle should be positive")
+	}
+
+	if t.Max < 0 {
+		issues.Addf("max", "max should be positive")
+	}
+
+	if t.Warning < 0 {
+		issues.Addf("warning", "warning should be positive")
+	}
+
+	if t.Idle > 0 && t.Warning >= t.Idle {
+		issues.Addf("warning", "warning should be less than idle")
+	}
+
+	return issues.Err()
+}
+
+// sessionModel wraps the screen models, it sends the time messages and
+// closes the session after the timeouts.
+type sessionModel struct {
+	model   tea.Model
+	session *model.Session
+	timeout Timeout
+	styles  *style.Styles
+
+	width     int
+	height    int
+	now       time.Time
+	lastInput time.Time
+	// closed is the reason of the close by the timeouts.
+	closed error
+}
+
+func newSessionModel(session *model.Session, m tea.Model, timeout Timeout) *sessionModel {
+	now := time.Now()
+
+	return &sessionModel{
+		model:     m,
+		session:   session,
+		timeout:   timeout,
+		styles:    style.DefaultStyles(),
+		now:       now,
+		lastInput: now,
+	}
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return model.TimeMsg(t)
+	})
+}
+
+func (m *sessionModel) Init() tea.Cmd {
+	return tea.Batch(m.model.Init(), tick())
+}
+
+func (m *sessionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg, tea.MouseMsg:
+		warned := m.idleLeft() <= m.timeout.Warning
+
+		m.lastInput = time.Now()
+
+		if m.timeout.Idle > 0 && warned {
+			// key only dismisses the warning, it is not for the view
+			return m, nil
+		}
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case model.TimeMsg:
+		m.now = time.Time(msg)
+
+		if err := m.expired(); err != nil {
+			m.closed = err
+			m.session.Audit(audit.Event{
+				Type:     audit.TypeTimeout,
+				Error:    err.Error(),
+				Duration: m.now.Sub(m.session.Start).Seconds(),
+			})
+
+			return m, tea.Quit
+		}
+
+		var cmd tea.Cmd
+		m.model, cmd = m.model.Update(msg)
+
+		return m, tea.Batch(cmd, tick())
+	}
+
+	var cmd tea.Cmd
+	m.model, cmd = m.model.Update(msg)
+
+	return m, cmd
+}
+
+// idleLeft returns the time to the idle timeout, max duration if disabled.
+func (m *sessionModel) idleLeft() time.Duration {
+	if m.timeout.Idle == 0 {
+		return time.Duration(1<<63 - 1)
+	}
+
+	return m.timeout.Idle - m.now.Sub(m.lastInput)
+}
+
+// maxLeft returns the time to the end of the session, max duration if disabled.
+func (m *sessionModel) maxLeft() time.Duration {
+	if m.timeout.Max == 0 {
+		return time.Duration(1<<63 - 1)
+	}
+
+	return m.timeout.Max - m.now.Sub(m.session.Start)
+}
+
+func (m *sessionModel) expired() error {
+	switch {
+	case m.maxLeft() <= 0:
+		return ErrSessionExpired
+	case m.idleLeft() <= 0:
+		return ErrIdleTimeout
+	}
+
+	return nil
+}
+
+// warning returns the countdown message, empty if it is not the time.
+func (m *sessionModel) warning() string {
+	maxLeft, idleLeft := m.maxLeft(), m.idleLeft()
+
+	switch {
+	case maxLeft <= m.timeout.Warning && maxLeft <= idleLeft:
+		return fmt.Sprintf("session ends in %s", style.Countdown(maxLeft))
+	case idleLeft <= m.timeout.Warning:
+		return fmt.Sprintf("session closes in %s without input, press any key to stay", style.Countdown(idleLeft))
+	}
+
+	return ""
+}
+
+func (m *sessionModel) View() string {
+	if m.closed != nil {
+		return ""
+	}
+
+	view := m.model.View()
+
+	warning := m.warning()
+	if warning == "" {
+		return view
+	}
+
+	// warning takes the last line of the screen
+	lines := strings.Split(view, "\n")
+	if m.height > 0 && len(lines) >= m.height {
+		lines = lines[:m.height-1]
+	}
+
+	bar := m.styles.Error.Copy().MarginTop(0).Width(m.width).Render(warning)
+
+	return lipgloss.JoinVertical(lipgloss.Left, strings.Join(lines, "\n"), bar)
+}
+
+// Closed returns the timeout reason of the program result, nil if the
+// session is closed by the user.
+func Closed(m tea.Model) error {
+	if s, ok := m.(*sessionModel); ok {
+		return s.closed
+	}
+
+	return nil
+}
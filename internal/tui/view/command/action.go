@@ -0,0 +1,251 @@
+package command
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rytsh/yap/internal/check"
+	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/view/confirm"
+)
+
+var ErrNoCommand = errors.New("no command")
+
+type Action struct {
+	Banner   string    `cfg:"banner"`
+	Commands []Command `cfg:"commands"`
+	// Strict fails the templates with missing keys, missing keys are empty if false.
+	Strict bool `cfg:"strict"`
+}
+
+// Command is a process or an HTTP request, args, dir, env and the request
+// fields are templates of the session values.
+type Command struct {
+	Name        string `cfg:"name"`
+	Description string `cfg:"description"`
+	// Args is the argv of the process, first one is the executable.
+	Args []string `cfg:"args"`
+	// HTTP is the request of the command, used instead of args.
+	HTTP *HTTPRequest `cfg:"http"`
+	Dir  string       `cfg:"dir"`
+	// Env is list of KEY=VALUE added to the InheritEnv variables of the server,
+	// form values are YAP_FORM_<NAME> variables.
+	Env     []string      `cfg:"env"`
+	Timeout time.Duration `cfg:"timeout"`
+	// Roles are required to run the command, one of them is enough.
+	Roles []string `cfg:"roles"`
+	// Confirm shows a dialog with the rendered command before it runs.
+	Confirm *confirm.Confirm `cfg:"confirm"`
+}
+
+func (a Action) GetNames() []string {
+	v := make([]string, len(a.Commands))
+	for i, c := range a.Commands {
+		v[i] = c.Name
+	}
+
+	return v
+}
+
+// Allowed returns the commands that the session can run.
+func (a Action) Allowed(session *model.Session) []Command {
+	v := make([]Command, 0, len(a.Commands))
+
+	for _, c := range a.Commands {
+		if session == nil || session.HasRole(c.Roles) {
+			v = append(v, c)
+		}
+	}
+
+	return v
+}
+
+// HTTPRequest is an HTTP call, the response is the output of the command.
+type HTTPRequest struct {
+	// Method is GET if empty.
+	Method  string            `cfg:"method"`
+	URL     string            `cfg:"url"`
+	Headers map[string]string `cfg:"headers" loggable:"false"`
+	Body    string            `cfg:"body"`
+}
+
+// Render returns the command with the executed templates.
+func (c Command) Render(data model.TemplateData, strict bool) (Command, error) {
+	var err error
+
+	render := func(text string) string {
+		if err != nil {
+			return ""
+		}
+
+		var v string
+		v, err = model.Render(text, data, strict)
+
+		return v
+	}
+
+	args := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		args[i] = render(a)
+	}
+
+	env := make([]string, len(c.Env))
+	for i, e := range c.Env {
+		env[i] = render(e)
+	}
+
+	c.Args = args
+	c.Env = env
+	c.Dir = render(c.Dir)
+
+	if c.HTTP != nil {
+		r := HTTPRequest{
+			Method:  c.HTTP.Method,
+			URL:     render(c.HTTP.URL),
+			Body:    render(c.HTTP.Body),
+			Headers: make(map[string]string, len(c.HTTP.Headers)),
+		}
+
+		for k, v := range c.HTTP.Headers {
+			r.Headers[k] = render(v)
+		}
+
+		c.HTTP = &r
+	}
+
+	return c, err
+}
+
+// Params returns the rendered parameters shown in the confirmation.
+func (c Command) Params() []confirm.Param {
+	if c.HTTP != nil {
+		return []confirm.Param{{Name: "method", Value: c.HTTP.Method}, {Name: "url", Value: c.HTTP.URL}}
+	}
+
+	args := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		args[i] = model.ShellQuote(a)
+	}
+
+	v := []confirm.Param{{Name: "command", Value: strings.Join(args, " ")}}
+	if c.Dir != "" {
+		v = append(v, confirm.Param{Name: "dir", Value: c.Dir})
+	}
+
+	return v
+}
+
+// templates returns the template fields by the config paths.
+func (c Command) templates() [][2]string {
+	v := [][2]string{{"dir", c.Dir}}
+
+	for i, a := range c.Args {
+		v = append(v, [2]string{check.Index("args", i), a})
+	}
+
+	for i, e := range c.Env {
+		v = append(v, [2]string{check.Index("env", i), e})
+	}
+
+	if c.HTTP != nil {
+		v = append(v, [2]string{"http.url", c.HTTP.URL}, [2]string{"http.body", c.HTTP.Body})
+
+		keys := make([]string, 0, len(c.HTTP.Headers))
+		for k := range c.HTTP.Headers {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			v = append(v, [2]string{check.Join("http.headers", k), c.HTTP.Headers[k]})
+		}
+	}
+
+	return v
+}
+
+// checkURL checks the scheme of the URL, templates are checked after the render.
+func checkURL(v string) error {
+	if v == "" {
+		return errors.New("url is required")
+	}
+
+	if model.IsTemplate(v) {
+		return nil
+	}
+
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("url should be http or https")
+	}
+
+	return nil
+}
+
+// FormEnv returns the form values as YAP_FORM_<NAME> variables.
+func FormEnv(values map[string]interface{}) []string {
+	env := make([]string, 0, len(values))
+	for name, v := range values {
+		env = append(env, "YAP_FORM_"+strings.ToUpper(name)+"="+model.FormatValue(v))
+	}
+
+	sort.Strings(env)
+
+	return env
+}
+
+// Validate checks the commands.
+func (a *Action) Validate() error {
+	var issues check.Issues
+
+	if len(a.Commands) == 0 {
+		issues.Addf("commands", "at least one command is required")
+	}
+
+	names := make(map[string]struct{}, len(a.Commands))
+	for i, c := range a.Commands {
+		path := check.Index("commands", i)
+
+		if c.Name == "" {
+			issues.Addf(check.Join(path, "name"), "name is required")
+		} else if _, ok := names[c.Name]; ok {
+			issues.Addf(check.Join(path, "name"), "duplicated command name %q", c.Name)
+		}
+
+		names[c.Name] = struct{}{}
+
+		switch {
+		case len(c.Args) == 0 && c.HTTP == nil:
+			issues.Addf(check.Join(path, "args"), "args or http is required")
+		case len(c.Args) > 0 && c.HTTP != nil:
+			issues.Addf(path, "only one of args and http is allowed")
+		}
+
+		if c.HTTP != nil {
+			if c.HTTP.Method == "" {
+				c.HTTP.Method = http.MethodGet
+			}
+
+			issues.Add(check.Join(path, "http.url"), checkURL(c.HTTP.URL))
+		}
+
+		for _, t := range c.templates() {
+			issues.Add(check.Join(path, t[0]), model.CheckTemplate(t[1]))
+		}
+
+		if c.Timeout < 0 {
+			issues.Addf(check.Join(path, "timeout"), "timeout should be positive")
+		}
+
+		if c.Confirm != nil {
+			issues.Add(check.Join(path, "confirm"), c.Confirm.Validate())
+		}
+	}
+
+	return issues.Err()
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rytsh/yap/internal/metric"
+)
+
+// request runs the HTTP command, status line is written to stderr and the
+// body to stdout. Exit code is zero for 1xx-3xx and the status code for others.
+func (r *Runner) request(ctx context.Context, c Command) {
+	start := time.Now()
+	exit := ExitMsg{ID: r.ID, Code: -1}
+
+	defer func() {
+		exit.Duration = time.Since(start)
+
+		switch {
+		case exit.Err != nil:
+			metric.CommandExecution(c.Name, metric.OutcomeError)
+		case exit.Code != 0:
+			metric.CommandExecution(c.Name, metric.OutcomeFailure)
+		default:
+			metric.CommandExecution(c.Name, metric.OutcomeSuccess)
+		}
+
+		r.send(exit)
+	}()
+
+	if err := checkURL(c.HTTP.URL); err != nil {
+		exit.Err = fmt.Errorf("rendered %w", err)
+
+		return
+	}
+
+	var body io.Reader
+	if c.HTTP.Body != "" {
+		body = strings.NewReader(c.HTTP.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.HTTP.Method, c.HTTP.URL, body)
+	if err != nil {
+		exit.Err = fmt.Errorf("request: %w", err)
+
+		return
+	}
+
+	for k, v := range c.HTTP.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		exit.Err = requestErr(ctx, c, err)
+
+		return
+	}
+
+	defer resp.Body.Close()
+
+	fmt.Fprintf(chanWriter{r: r, stderr: true}, "%s %s\n", resp.Proto, resp.Status)
+
+	if _, err := io.Copy(chanWriter{r: r}, resp.Body); err != nil {
+		exit.Err = requestErr(ctx, c, err)
+
+		return
+	}
+
+	exit.Code = 0
+	if resp.StatusCode >= http.StatusBadRequest {
+		exit.Code = resp.StatusCode
+	}
+}
+
+func requestErr(ctx context.Context, c Command, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("timeout after %s", c.Timeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("canceled")
+	}
+
+	return err
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package command
+
+import (
+	"os/exec"
+)
+
+func setGroup(_ *exec.Cmd) {}
+
+func killGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+
+	return cmd.Process.Kill() //nolint:wrapcheck // no need
+}
@@ -0,0 +1,193 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rytsh/yap/internal/metric"
+)
+
+// WaitDelay is the time to wait for output pipes after the process is killed.
+var WaitDelay = 2 * time.Second
+
+// InheritEnv are the variables of the server passed to the processes, others
+// like the secrets of the server are not visible to the commands.
+var InheritEnv = []string{"PATH", "HOME", "LANG", "LC_ALL", "TZ", "TMPDIR"}
+
+// OutputMsg is a chunk of the process output.
+type OutputMsg struct {
+	ID     int
+	Data   string
+	Stderr bool
+}
+
+// ExitMsg is sent once when the process is finished.
+type ExitMsg struct {
+	ID       int
+	Code     int
+	Err      error
+	Duration time.Duration
+}
+
+// Runner streams output of one process as tea messages.
+type Runner struct {
+	ID int
+
+	ch       chan tea.Msg
+	quit     chan struct{}
+	quitOnce sync.Once
+	cancel   context.CancelFunc
+	// done is closed with the session, nobody reads the messages after it.
+	done <-chan struct{}
+}
+
+type chanWriter struct {
+	r      *Runner
+	stderr bool
+}
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w.r.send(OutputMsg{ID: w.r.ID, Data: string(p), Stderr: w.stderr})
+
+	return len(p), nil
+}
+
+// Start runs the command in background, use Wait to get messages. The process
+// is killed when the session context is done.
+func Start(session context.Context, id int, c Command) *Runner {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	if c.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(session, c.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(session)
+	}
+
+	r := &Runner{
+		ID:     id,
+		ch:     make(chan tea.Msg, 64),
+		quit:   make(chan struct{}),
+		cancel: cancel,
+		done:   session.Done(),
+	}
+
+	go r.run(ctx, c)
+
+	return r
+}
+
+func (r *Runner) run(ctx context.Context, c Command) {
+	defer r.cancel()
+
+	if c.HTTP != nil {
+		r.request(ctx, c)
+
+		return
+	}
+
+	if len(c.Args) == 0 {
+		metric.CommandExecution(c.Name, metric.OutcomeError)
+		r.send(ExitMsg{ID: r.ID, Code: -1, Err: ErrNoCommand})
+
+		return
+	}
+
+	cmd := exec.CommandContext(ctx, c.Args[0], c.Args[1:]...) //nolint:gosec // commands come from configuration
+	cmd.Dir = c.Dir
+	cmd.Env = append(inheritEnv(), c.Env...)
+	cmd.Stdout = chanWriter{r: r}
+	cmd.Stderr = chanWriter{r: r, stderr: true}
+	cmd.WaitDelay = WaitDelay
+	cmd.Cancel = func() error {
+		return killGroup(cmd)
+	}
+
+	setGroup(cmd)
+
+	start := time.Now()
+	err := cmd.Run()
+
+	exit := ExitMsg{ID: r.ID, Duration: time.Since(start), Code: -1}
+	if cmd.ProcessState != nil {
+		exit.Code = cmd.ProcessState.ExitCode()
+	}
+
+	var exitErr *exec.ExitError
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		exit.Err = fmt.Errorf("timeout after %s", c.Timeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		exit.Err = fmt.Errorf("canceled")
+	case err != nil && !errors.As(err, &exitErr):
+		exit.Err = err
+	}
+
+	switch {
+	case exit.Err != nil:
+		metric.CommandExecution(c.Name, metric.OutcomeError)
+	case exit.Code != 0:
+		metric.CommandExecution(c.Name, metric.OutcomeFailure)
+	default:
+		metric.CommandExecution(c.Name, metric.OutcomeSuccess)
+	}
+
+	r.send(exit)
+}
+
+// inheritEnv returns the InheritEnv variables of the server.
+func inheritEnv() []string {
+	env := make([]string, 0, len(InheritEnv))
+
+	for _, k := range InheritEnv {
+		if v, ok := os.LookupEnv(k); ok {
+			env = append(env, k+"="+v)
+		}
+	}
+
+	return env
+}
+
+// send drops the message if nobody is listening anymore.
+func (r *Runner) send(msg tea.Msg) {
+	select {
+	case r.ch <- msg:
+	case <-r.quit:
+	case <-r.done:
+	}
+}
+
+// Wait returns the next message of the process.
+func (r *Runner) Wait() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case msg := <-r.ch:
+			return msg
+		case <-r.quit:
+			return nil
+		case <-r.done:
+			return nil
+		}
+	}
+}
+
+// Cancel kills the process group, exit message still sent.
+func (r *Runner) Cancel() {
+	r.cancel()
+}
+
+// Close kills the process and drops remaining messages.
+func (r *Runner) Close() {
+	r.cancel()
+	r.quitOnce.Do(func() { close(r.quit) })
+}
@@ -0,0 +1,134 @@
+//go:build !windows
+
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunnerSessionDone(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// floods the output so the runner blocks on the full channel
+	r := Start(ctx, 1, Command{
+		Name: "flood",
+		Args: []string{"sh", "-c", `echo $$ > "$0"; while :; do echo flood; done`, pidFile},
+	})
+	defer r.Close()
+
+	pid := waitPid(t, pidFile)
+
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for syscall.Kill(pid, 0) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("process %d is still running after the session is done", pid)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		_ = r.Wait()()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait() blocks after the session is done")
+	}
+}
+
+func TestRunnerCancel(t *testing.T) {
+	r := Start(context.Background(), 2, Command{Name: "sleep", Args: []string{"sleep", "10"}})
+	defer r.Close()
+
+	r.Cancel()
+
+	for {
+		msg := r.Wait()()
+
+		exit, ok := msg.(ExitMsg)
+		if !ok {
+			continue
+		}
+
+		if exit.ID != 2 || exit.Err == nil || exit.Err.Error() != "canceled" {
+			t.Fatalf("exit = %+v, want canceled", exit)
+		}
+
+		return
+	}
+}
+
+func waitPid(t *testing.T, path string) int {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		content, err := os.ReadFile(path)
+		if err == nil && strings.HasSuffix(string(content), "\n") {
+			pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+			if err != nil {
+				t.Fatalf("pid file: %v", err)
+			}
+
+			return pid
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("process is not started")
+
+	return 0
+}
+
+func TestRunnerEnv(t *testing.T) {
+	t.Setenv("YAP_TEST_SECRET", "secret")
+	t.Setenv("LANG", "C.UTF-8")
+
+	r := Start(context.Background(), 3, Command{
+		Name: "env",
+		Args: []string{"sh", "-c", `echo "secret=$YAP_TEST_SECRET lang=$LANG custom=$CUSTOM"`},
+		Env:  []string{"CUSTOM=value"},
+	})
+	defer r.Close()
+
+	var out strings.Builder
+
+	for {
+		switch msg := r.Wait()().(type) {
+		case OutputMsg:
+			out.WriteString(msg.Data)
+
+			continue
+		case ExitMsg:
+			if msg.Code != 0 || msg.Err != nil {
+				t.Fatalf("exit = %+v, output %q", msg, out.String())
+			}
+		}
+
+		break
+	}
+
+	if got, want := strings.TrimSpace(out.String()), "secret= lang=C.UTF-8 custom=value"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
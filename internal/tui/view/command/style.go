@@ -0,0 +1,61 @@
+package command
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rytsh/yap/internal/tui/style"
+)
+
+// styles of the view, built from the theme.
+type styles struct {
+	list         lipgloss.Style
+	item         lipgloss.Style
+	selectedItem lipgloss.Style
+	output       lipgloss.Style
+	status       lipgloss.Style
+	stderr       lipgloss.Style
+	fail         lipgloss.Style
+	info         lipgloss.Style
+	banner       lipgloss.Style
+	checkMark    string
+	// base is the theme of the dialogs.
+	base *style.Styles
+}
+
+func newStyles(s *style.Styles) styles {
+	return styles{
+		base: s,
+
+		list: lipgloss.NewStyle().
+			Border(s.Border).
+			BorderForeground(s.Highlight).
+			Width(style.ColumnWidth).
+			MarginRight(1),
+
+		item:         lipgloss.NewStyle().PaddingLeft(2),
+		selectedItem: s.Focused.Copy(),
+
+		output: lipgloss.NewStyle().
+			Border(s.Border).
+			BorderForeground(s.Highlight),
+
+		status: lipgloss.NewStyle().
+			BorderStyle(lipgloss.NormalBorder()).
+			BorderBottom(true).
+			BorderForeground(s.Subtle),
+
+		stderr: s.Fail.Copy(),
+		fail:   s.Fail.Copy(),
+
+		info: s.Blurred.Copy().PaddingLeft(1),
+
+		banner: lipgloss.NewStyle().
+			Border(s.BannerBorder).
+			BorderTop(true).
+			BorderBottom(true).
+			BorderLeft(false).
+			BorderRight(false).
+			Padding(0, 1),
+
+		checkMark: s.CheckMark,
+	}
+}
@@ -0,0 +1,462 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/style"
+	"github.com/rytsh/yap/internal/tui/view/confirm"
+)
+
+// MaxOutput is the kept size of the output, older parts are dropped.
+var MaxOutput = 1 << 20
+
+type CommandModel struct {
+	width    int
+	height   int
+	keymap   keymapCommand
+	help     help.Model
+	viewport viewport.Model
+	time     time.Time
+
+	index   model.Index
+	session *model.Session
+
+	action Action
+	// commands are the allowed commands of the session.
+	commands []Command
+	cursor   int
+	styles   styles
+
+	runner   *Runner
+	runID    int
+	running  bool
+	started  time.Time
+	output   string
+	exit     *ExitMsg
+	lastName string
+
+	// dialog is open until the pending command is confirmed.
+	dialog  *confirm.Dialog
+	pending Command
+	// pendingMasked is the pending command without the passwords.
+	pendingMasked Command
+}
+
+type keymapCommand = struct {
+	up, down, run, cancel, back, quit key.Binding
+}
+
+func NewCommandModel(action Action) *CommandModel {
+	vp := viewport.New(0, 0)
+	vp.KeyMap = viewport.KeyMap{
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown"),
+			key.WithHelp("pgdn", "page down"),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup"),
+			key.WithHelp("pgup", "page up"),
+		),
+		HalfPageUp: key.NewBinding(
+			key.WithKeys("ctrl+u"),
+		),
+		HalfPageDown: key.NewBinding(
+			key.WithKeys("ctrl+d"),
+		),
+	}
+
+	m := CommandModel{
+		action:   action,
+		commands: action.Commands,
+		styles:   newStyles(style.DefaultStyles()),
+		help:     help.New(),
+		viewport: vp,
+		keymap: keymapCommand{
+			up: key.NewBinding(
+				key.WithKeys("up", "k"),
+				key.WithHelp("↑/k", "up"),
+			),
+			down: key.NewBinding(
+				key.WithKeys("down", "j"),
+				key.WithHelp("↓/j", "down"),
+			),
+			run: key.NewBinding(
+				key.WithKeys("enter"),
+				key.WithHelp("enter", "run"),
+			),
+			cancel: key.NewBinding(
+				key.WithKeys("ctrl+x"),
+				key.WithHelp("ctrl+x", "cancel"),
+			),
+			back: key.NewBinding(
+				key.WithKeys("esc"),
+				key.WithHelp("esc", "back"),
+			),
+			quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "quit"),
+			),
+		},
+	}
+
+	return &m
+}
+
+func (m *CommandModel) SetIndex(index model.Index) {
+	m.index = index
+}
+
+func (m *CommandModel) Initialize(cfg model.Config) tea.Cmd {
+	m.width = cfg.Width
+	m.height = cfg.Height
+	m.session = cfg.Session
+	m.commands = m.action.Allowed(m.session)
+
+	if m.cursor >= len(m.commands) {
+		m.cursor = 0
+	}
+
+	if cfg.Styles != nil {
+		m.styles = newStyles(cfg.Styles)
+	}
+
+	m.resize()
+
+	return m.Init()
+}
+
+func (m *CommandModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *CommandModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.keymap.quit):
+			m.close()
+			return m, tea.Quit
+		case m.dialog != nil:
+			return m.confirm(msg)
+		case key.Matches(msg, m.keymap.cancel):
+			if m.running {
+				m.runner.Cancel()
+			}
+			return m, nil
+		case key.Matches(msg, m.keymap.back):
+			if m.running {
+				return m, nil
+			}
+			m.close()
+			return m.index.PrevModel(model.Config{
+				Width:  m.width,
+				Height: m.height,
+			})
+		case key.Matches(msg, m.keymap.up):
+			if m.cursor > 0 {
+				m.cursor--
+			}
+			return m, nil
+		case key.Matches(msg, m.keymap.down):
+			if m.cursor < len(m.commands)-1 {
+				m.cursor++
+			}
+			return m, nil
+		case key.Matches(msg, m.keymap.run):
+			return m.run()
+		}
+	case OutputMsg:
+		if msg.ID != m.runID {
+			return m, nil
+		}
+
+		m.write(msg.Data, msg.Stderr)
+
+		return m, m.runner.Wait()
+	case ExitMsg:
+		if msg.ID != m.runID {
+			return m, nil
+		}
+
+		m.running = false
+		m.exit = &msg
+
+		result := audit.ResultSuccess
+		switch {
+		case msg.Err != nil:
+			result = audit.ResultError
+		case msg.Code != 0:
+			result = audit.ResultFailure
+		}
+
+		m.session.Audit(audit.Event{
+			Type:     audit.TypeAction,
+			Action:   "command",
+			Name:     m.lastName,
+			Params:   map[string]interface{}{"exit_code": msg.Code},
+			Result:   result,
+			Error:    audit.ErrString(msg.Err),
+			Duration: msg.Duration.Seconds(),
+		})
+
+		return m, nil
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		m.width = msg.Width
+		m.resize()
+	case model.TimeMsg:
+		m.time = time.Time(msg)
+	}
+
+	if m.dialog != nil {
+		_, cmd := m.dialog.Update(msg)
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+
+	return m, cmd
+}
+
+// confirm sends the key to the dialog, the pending command starts if accepted.
+func (m *CommandModel) confirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	result, cmd := m.dialog.Update(msg)
+
+	switch result {
+	case confirm.Accepted:
+		m.dialog = nil
+		return m.start(m.pending, m.pendingMasked)
+	case confirm.Canceled:
+		m.dialog = nil
+		m.session.Audit(audit.Event{
+			Type:   audit.TypeAction,
+			Action: "command",
+			Name:   m.pending.Name,
+			Result: audit.ResultCanceled,
+		})
+	}
+
+	return m, cmd
+}
+
+func (m *CommandModel) run() (tea.Model, tea.Cmd) {
+	if m.running || len(m.commands) == 0 {
+		return m, nil
+	}
+
+	c := m.commands[m.cursor]
+
+	if !m.session.HasRole(c.Roles) {
+		// roles may be changed after the view is opened
+		m.session.Audit(audit.Event{
+			Type:   audit.TypeAction,
+			Action: "command",
+			Name:   c.Name,
+			Result: audit.ResultFailure,
+			Error:  model.ErrDenied.Error(),
+		})
+
+		return m, nil
+	}
+
+	// masked is shown and logged without the passwords of the forms
+	masked, _ := c.Render(m.session.MaskedTemplateData(), m.action.Strict)
+
+	c, err := c.Render(m.session.TemplateData(), m.action.Strict)
+	if err == nil && c.Confirm != nil {
+		m.dialog, err = confirm.New(*c.Confirm, m.session.MaskedTemplateData(), m.action.Strict, masked.Params(), m.styles.base)
+		if err == nil {
+			m.pending = c
+			m.pendingMasked = masked
+
+			return m, m.dialog.Init()
+		}
+	}
+
+	if err != nil {
+		m.reset(c.Name)
+		m.exit = &ExitMsg{ID: m.runID, Code: -1, Err: err}
+		m.session.Audit(audit.Event{
+			Type:   audit.TypeAction,
+			Action: "command",
+			Name:   c.Name,
+			Result: audit.ResultError,
+			Error:  err.Error(),
+		})
+
+		return m, nil
+	}
+
+	return m.start(c, masked)
+}
+
+// reset closes the previous run and clears the output.
+func (m *CommandModel) reset(name string) {
+	m.close()
+	m.runID++
+	m.started = time.Now()
+	m.output = ""
+	m.lastName = name
+	m.viewport.SetContent("")
+}
+
+// start runs the rendered command, masked is the command logged.
+func (m *CommandModel) start(c, masked Command) (tea.Model, tea.Cmd) {
+	m.reset(c.Name)
+
+	// form values are before the command env, so the command can override them
+	c.Env = append(FormEnv(m.session.Form()), c.Env...)
+
+	m.runner = Start(m.session.Context(), m.runID, c)
+
+	params := map[string]interface{}{"args": masked.Args, "dir": masked.Dir}
+	if masked.HTTP != nil {
+		params = map[string]interface{}{"method": masked.HTTP.Method, "url": masked.HTTP.URL}
+	}
+
+	m.session.Audit(audit.Event{
+		Type:   audit.TypeAction,
+		Action: "command",
+		Name:   c.Name,
+		Params: params,
+		Result: audit.ResultStarted,
+	})
+	m.running = true
+	m.exit = nil
+
+	return m, m.runner.Wait()
+}
+
+// close kills the previous process if it is still there.
+func (m *CommandModel) close() {
+	if m.runner != nil {
+		m.runner.Close()
+	}
+}
+
+func (m *CommandModel) write(data string, stderr bool) {
+	if stderr {
+		data = m.styles.stderr.Render(data)
+	}
+
+	atBottom := m.viewport.AtBottom()
+
+	m.output += data
+	if len(m.output) > MaxOutput {
+		cut := len(m.output) - MaxOutput
+		if i := strings.IndexByte(m.output[cut:], '\n'); i >= 0 {
+			cut += i + 1
+		}
+
+		m.output = m.output[cut:]
+	}
+
+	m.viewport.SetContent(m.output)
+
+	if atBottom {
+		m.viewport.GotoBottom()
+	}
+}
+
+func (m *CommandModel) resize() {
+	height := m.height - 8
+	if m.action.Banner != "" {
+		height -= 2
+	}
+
+	m.viewport.Width = style.Max(10, m.width-style.ColumnWidth-8)
+	m.viewport.Height = style.Max(3, height)
+}
+
+func (m *CommandModel) status() string {
+	switch {
+	case m.running:
+		elapsed := m.time.Sub(m.started)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+
+		return fmt.Sprintf("running %s · %s", m.lastName, elapsed.Truncate(time.Second))
+	case m.exit != nil:
+		text := fmt.Sprintf("%s exit %d · %s", m.lastName, m.exit.Code, m.exit.Duration.Truncate(time.Millisecond))
+		if m.exit.Err != nil {
+			text += " · " + m.exit.Err.Error()
+		}
+
+		if m.exit.Code == 0 && m.exit.Err == nil {
+			return m.styles.checkMark + text
+		}
+
+		return m.styles.fail.Render(text)
+	case len(m.commands) > 0:
+		c := m.commands[m.cursor]
+		switch {
+		case c.Description != "":
+			return c.Description
+		case c.HTTP != nil:
+			return c.HTTP.Method + " " + c.HTTP.URL
+		}
+
+		return strings.Join(c.Args, " ")
+	}
+
+	return "no commands"
+}
+
+func (m *CommandModel) View() string {
+	help := m.help.ShortHelpView([]key.Binding{
+		m.keymap.up,
+		m.keymap.down,
+		m.keymap.run,
+		m.keymap.cancel,
+		m.viewport.KeyMap.PageUp,
+		m.viewport.KeyMap.PageDown,
+		m.keymap.back,
+		m.keymap.quit,
+	})
+
+	items := make([]string, 0, len(m.commands))
+	for i, c := range m.commands {
+		if i == m.cursor {
+			items = append(items, m.styles.selectedItem.Render("> "+c.Name))
+			continue
+		}
+
+		items = append(items, m.styles.item.Render(c.Name))
+	}
+
+	list := m.styles.list.Height(m.viewport.Height + 2).Render(lipgloss.JoinVertical(lipgloss.Left, items...))
+
+	output := m.styles.output.Render(lipgloss.JoinVertical(lipgloss.Left,
+		m.styles.status.Width(m.viewport.Width).Render(m.status()),
+		m.viewport.View(),
+	))
+
+	ui := lipgloss.JoinHorizontal(lipgloss.Top, list, output)
+
+	if m.action.Banner != "" {
+		ui = lipgloss.JoinVertical(lipgloss.Left, m.styles.banner.Render(m.action.Banner), ui)
+	}
+
+	if identity := m.session.Identity(); identity != "" {
+		ui = lipgloss.JoinVertical(lipgloss.Left, ui, m.styles.info.Render("logged in as "+identity))
+	}
+
+	ui += "\n\n" + help
+
+	if m.dialog != nil {
+		return m.dialog.View(style.Max(lipgloss.Width(ui), m.width), style.Max(lipgloss.Height(ui), m.height))
+	}
+
+	return ui
+}
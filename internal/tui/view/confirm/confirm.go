@@ -0,0 +1,235 @@
+// Package confirm is the dialog shown before the marked actions.
+package confirm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rytsh/yap/internal/check"
+	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/style"
+)
+
+// DefaultMessage is the message of the dialog if it is not set.
+var DefaultMessage = "Are you sure?"
+
+// Confirm asks the user before the action.
+type Confirm struct {
+	// Message is a template of the session values.
+	Message string `cfg:"message"`
+	// Phrase should be typed to continue, it is a template like "{{.Form.env}}".
+	Phrase string `cfg:"phrase"`
+}
+
+func (c *Confirm) Validate() error {
+	var issues check.Issues
+
+	issues.Add("message", model.CheckTemplate(c.Message))
+	issues.Add("phrase", model.CheckTemplate(c.Phrase))
+
+	return issues.Err()
+}
+
+// Result is the state of the dialog after a key.
+type Result int
+
+const (
+	Pending Result = iota
+	Accepted
+	Canceled
+)
+
+// Param is a rendered parameter of the action.
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Dialog is the modal of the confirmation, the view sends the keys to it
+// while it is open.
+type Dialog struct {
+	message string
+	phrase  string
+	params  []Param
+
+	input textinput.Model
+	// accept is true if the confirm button is focused.
+	accept bool
+	err    string
+
+	keymap keymapDialog
+	styles *style.Styles
+}
+
+type keymapDialog = struct {
+	switchButton, enter, cancel, yes, no key.Binding
+}
+
+// New renders the message and the phrase of the confirmation.
+func New(c Confirm, data model.TemplateData, strict bool, params []Param, s *style.Styles) (*Dialog, error) {
+	message, err := model.Render(c.Message, data, strict)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // no need
+	}
+
+	if message == "" {
+		message = DefaultMessage
+	}
+
+	phrase, err := model.Render(c.Phrase, data, strict)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // no need
+	}
+
+	d := &Dialog{
+		message: message,
+		phrase:  phrase,
+		params:  params,
+		styles:  s,
+		keymap: keymapDialog{
+			switchButton: key.NewBinding(key.WithKeys("tab", "shift+tab", "left", "right")),
+			enter:        key.NewBinding(key.WithKeys("enter")),
+			cancel:       key.NewBinding(key.WithKeys("esc")),
+			yes:          key.NewBinding(key.WithKeys("y")),
+			no:           key.NewBinding(key.WithKeys("n")),
+		},
+	}
+
+	if phrase != "" {
+		d.input = textinput.New()
+		d.input.Prompt = "> "
+		d.input.PromptStyle = s.Focused
+		d.input.TextStyle = s.Focused
+		d.input.Focus()
+		d.accept = true
+	}
+
+	return d, nil
+}
+
+// Init returns the blink of the phrase input.
+func (d *Dialog) Init() tea.Cmd {
+	if d.phrase != "" {
+		return textinput.Blink
+	}
+
+	return nil
+}
+
+func (d *Dialog) Update(msg tea.Msg) (Result, tea.Cmd) {
+	k, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		if d.phrase != "" {
+			d.input, cmd = d.input.Update(msg)
+		}
+
+		return Pending, cmd
+	}
+
+	switch {
+	case key.Matches(k, d.keymap.cancel):
+		return Canceled, nil
+	case key.Matches(k, d.keymap.enter):
+		if !d.accept {
+			return Canceled, nil
+		}
+
+		if d.phrase != "" && strings.TrimSpace(d.input.Value()) != d.phrase {
+			d.err = fmt.Sprintf("type %q to continue", d.phrase)
+
+			return Pending, nil
+		}
+
+		return Accepted, nil
+	case key.Matches(k, d.keymap.switchButton) && d.phrase == "":
+		d.accept = !d.accept
+
+		return Pending, nil
+	case d.phrase == "" && key.Matches(k, d.keymap.yes):
+		return Accepted, nil
+	case d.phrase == "" && key.Matches(k, d.keymap.no):
+		return Canceled, nil
+	}
+
+	if d.phrase == "" {
+		return Pending, nil
+	}
+
+	var cmd tea.Cmd
+	d.input, cmd = d.input.Update(msg)
+	d.err = ""
+
+	return Pending, cmd
+}
+
+// View renders the dialog in the center of the area.
+func (d *Dialog) View(width, height int) string {
+	innerWidth := style.Max(20, style.Min(60, width-6))
+
+	var b strings.Builder
+
+	b.WriteString(lipgloss.NewStyle().Bold(true).Width(innerWidth).Render(d.message))
+
+	if len(d.params) > 0 {
+		nameWidth := 0
+		for _, p := range d.params {
+			nameWidth = style.Max(nameWidth, lipgloss.Width(p.Name))
+		}
+
+		b.WriteString("\n")
+
+		for _, p := range d.params {
+			b.WriteString("\n")
+			b.WriteString(d.styles.Blurred.Copy().Width(nameWidth + 2).Render(p.Name + ":"))
+			b.WriteString(truncate(p.Value, innerWidth-nameWidth-2))
+		}
+	}
+
+	help := "y confirm • n cancel • tab switch"
+
+	if d.phrase != "" {
+		b.WriteString("\n\nType ")
+		b.WriteString(d.styles.Focused.Render(d.phrase))
+		b.WriteString(" to continue\n")
+		b.WriteString(d.input.View())
+
+		help = "enter confirm • esc cancel"
+	}
+
+	confirmButton, cancelButton := d.styles.Button, d.styles.ActiveButton
+	if d.accept {
+		confirmButton, cancelButton = d.styles.ActiveButton, d.styles.Button
+	}
+
+	buttons := lipgloss.JoinHorizontal(lipgloss.Top,
+		confirmButton.Copy().MarginRight(2).Render("Confirm"),
+		cancelButton.Render("Cancel"),
+	)
+
+	box := d.styles.DialogBox.Render(lipgloss.JoinVertical(lipgloss.Center,
+		lipgloss.NewStyle().Width(innerWidth).Render(b.String()),
+		buttons,
+		d.styles.Fail.Render(d.err),
+		d.styles.Blurred.Render(help),
+	))
+
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, box)
+}
+
+// truncate cuts the value to the width, new lines are shown as spaces.
+func truncate(v string, width int) string {
+	v = strings.ReplaceAll(v, "\n", " ")
+
+	runes := []rune(v)
+	if width > 1 && len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+
+	return v
+}
@@ -0,0 +1,194 @@
+package confirm
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/style"
+)
+
+func keys(v ...string) []tea.KeyMsg {
+	msgs := make([]tea.KeyMsg, 0, len(v))
+
+	for _, k := range v {
+		switch k {
+		case "enter":
+			msgs = append(msgs, tea.KeyMsg{Type: tea.KeyEnter})
+		case "esc":
+			msgs = append(msgs, tea.KeyMsg{Type: tea.KeyEsc})
+		case "tab":
+			msgs = append(msgs, tea.KeyMsg{Type: tea.KeyTab})
+		default:
+			for _, r := range k {
+				msgs = append(msgs, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+			}
+		}
+	}
+
+	return msgs
+}
+
+func TestDialogUpdate(t *testing.T) {
+	data := model.TemplateData{Form: map[string]string{"env": "prod"}}
+
+	tests := []struct {
+		name    string
+		confirm Confirm
+		keys    []string
+		want    Result
+		// wantErr is true if the dialog shows an error
+		wantErr bool
+	}{
+		{name: "yes", keys: []string{"y"}, want: Accepted},
+		{name: "no", keys: []string{"n"}, want: Canceled},
+		// cancel is focused first
+		{name: "enter on cancel", keys: []string{"enter"}, want: Canceled},
+		{name: "enter on confirm", keys: []string{"tab", "enter"}, want: Accepted},
+		{name: "switch twice", keys: []string{"tab", "tab", "enter"}, want: Canceled},
+		{name: "esc", keys: []string{"esc"}, want: Canceled},
+		{name: "other keys", keys: []string{"x"}, want: Pending},
+		{
+			name:    "phrase is typed",
+			confirm: Confirm{Phrase: "{{.Form.env}}"},
+			keys:    []string{"prod", "enter"},
+			want:    Accepted,
+		},
+		{
+			name:    "phrase mismatch",
+			confirm: Confirm{Phrase: "{{.Form.env}}"},
+			keys:    []string{"pro", "enter"},
+			want:    Pending,
+			wantErr: true,
+		},
+		{
+			name:    "phrase is case sensitive",
+			confirm: Confirm{Phrase: "{{.Form.env}}"},
+			keys:    []string{"PROD", "enter"},
+			want:    Pending,
+			wantErr: true,
+		},
+		{
+			name:    "phrase is typed after a mismatch",
+			confirm: Confirm{Phrase: "{{.Form.env}}"},
+			keys:    []string{"pro", "enter", "d", "enter"},
+			want:    Accepted,
+		},
+		{
+			name:    "y is typed in the phrase",
+			confirm: Confirm{Phrase: "yes"},
+			keys:    []string{"y"},
+			want:    Pending,
+		},
+		{
+			name:    "esc with a phrase",
+			confirm: Confirm{Phrase: "prod"},
+			keys:    []string{"pr", "esc"},
+			want:    Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := New(tt.confirm, data, true, nil, style.DefaultStyles())
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			got := Pending
+			for _, k := range keys(tt.keys...) {
+				if got, _ = d.Update(k); got != Pending {
+					break
+				}
+			}
+
+			if got != tt.want {
+				t.Errorf("Update() = %v, want %v", got, tt.want)
+			}
+
+			if (d.err != "") != tt.wantErr {
+				t.Errorf("error = %q, wantErr %v", d.err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	data := model.TemplateData{User: "alice", Form: map[string]string{"env": "prod"}}
+
+	tests := []struct {
+		name        string
+		confirm     Confirm
+		strict      bool
+		wantMessage string
+		wantPhrase  string
+		wantErr     bool
+	}{
+		{name: "default message", wantMessage: DefaultMessage},
+		{
+			name:        "templates",
+			confirm:     Confirm{Message: "Deploy {{.Form.env}} as {{.User}}?", Phrase: "{{.Form.env}}"},
+			strict:      true,
+			wantMessage: "Deploy prod as alice?",
+			wantPhrase:  "prod",
+		},
+		{
+			name:        "missing key",
+			confirm:     Confirm{Message: "Deploy {{.Form.host}}?"},
+			wantMessage: "Deploy ?",
+		},
+		{
+			name:    "missing key in strict mode",
+			confirm: Confirm{Message: "Deploy {{.Form.host}}?"},
+			strict:  true,
+			wantErr: true,
+		},
+		{
+			name:    "missing phrase key in strict mode",
+			confirm: Confirm{Phrase: "{{.Form.host}}"},
+			strict:  true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := New(tt.confirm, data, tt.strict, []Param{{Name: "command", Value: "deploy"}}, style.DefaultStyles())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if d.message != tt.wantMessage || d.phrase != tt.wantPhrase {
+				t.Errorf("New() = %q, %q, want %q, %q", d.message, d.phrase, tt.wantMessage, tt.wantPhrase)
+			}
+
+			if view := d.View(80, 24); !strings.Contains(view, "deploy") {
+				t.Errorf("View() does not show the params:\n%s", view)
+			}
+		})
+	}
+}
+
+func TestConfirmValidate(t *testing.T) {
+	tests := []struct {
+		confirm Confirm
+		wantErr bool
+	}{
+		{confirm: Confirm{}},
+		{confirm: Confirm{Message: "Deploy {{.Form.env}}?", Phrase: "{{.Form.env}}"}},
+		{confirm: Confirm{Message: "{{ if .Form.env }}"}, wantErr: true},
+		{confirm: Confirm{Phrase: "{{ unknown .Form.env }}"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.confirm.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() of %+v error = %v, wantErr %v", tt.confirm, err, tt.wantErr)
+		}
+	}
+}
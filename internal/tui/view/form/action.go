@@ -0,0 +1,279 @@
+package form
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rytsh/yap/internal/check"
+	"github.com/rytsh/yap/internal/tui/view/confirm"
+)
+
+// Field types.
+const (
+	TypeText        = "text"
+	TypePassword    = "password"
+	TypeNumber      = "number"
+	TypeSelect      = "select"
+	TypeMultiSelect = "multi_select"
+	TypeCheckbox    = "checkbox"
+	TypeMultiLine   = "multi_line"
+)
+
+var ErrRequired = errors.New("value is required")
+
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Action asks the values of the fields, the values are kept in the session
+// for the next views.
+type Action struct {
+	Banner string  `cfg:"banner"`
+	Fields []Field `cfg:"fields"`
+	// Submit is the label of the submit button.
+	Submit string `cfg:"submit"`
+	// Confirm shows a dialog with the values before they are submitted,
+	// templates see the new values.
+	Confirm *confirm.Confirm `cfg:"confirm"`
+}
+
+type Field struct {
+	// Name is the key of the value, letters, digits and underscores.
+	Name string `cfg:"name"`
+	// Label is shown above the input, name is used if empty.
+	Label string `cfg:"label"`
+	// Type is text, password, number, select, multi_select, checkbox or multi_line.
+	Type string `cfg:"type"`
+	// Help is shown under the input.
+	Help        string `cfg:"help"`
+	Placeholder string `cfg:"placeholder"`
+	Required    bool   `cfg:"required"`
+	// Default is the initial value, comma separated options for multi_select
+	// and true or false for checkbox.
+	Default string `cfg:"default"`
+	// Options are the choices of select and multi_select.
+	Options []string `cfg:"options"`
+	// Pattern is the regular expression of the text values.
+	Pattern string `cfg:"pattern"`
+	// Message is shown if the value does not match the pattern.
+	Message string `cfg:"message"`
+	// Min and Max are the range of number values and the length of text values.
+	Min *float64 `cfg:"min"`
+	Max *float64 `cfg:"max"`
+	// Rows is the height of multi_line, default is 4.
+	Rows int `cfg:"rows"`
+
+	pattern *regexp.Regexp `cfg:"-"`
+}
+
+// Title returns the label of the field.
+func (f Field) Title() string {
+	if f.Label != "" {
+		return f.Label
+	}
+
+	return f.Name
+}
+
+// IsText returns true if the value is typed.
+func (f Field) IsText() bool {
+	switch f.Type {
+	case TypeText, TypePassword, TypeNumber, TypeMultiLine:
+		return true
+	}
+
+	return false
+}
+
+// Parse checks the typed value and returns it as a string or a float64 for
+// number fields, empty values are nil.
+func (f Field) Parse(raw string) (interface{}, error) {
+	if f.Type != TypeMultiLine {
+		raw = strings.TrimSpace(raw)
+	}
+
+	// blank multi_line values are empty, other values keep their spaces
+	if strings.TrimSpace(raw) == "" {
+		if f.Required {
+			return nil, ErrRequired
+		}
+
+		return nil, nil
+	}
+
+	if f.Type == TypeNumber {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+
+		if err := f.inRange(v, "value"); err != nil {
+			return nil, err
+		}
+
+		return v, nil
+	}
+
+	if err := f.inRange(float64(len([]rune(raw))), "length"); err != nil {
+		return nil, err
+	}
+
+	if f.pattern != nil && !f.pattern.MatchString(raw) {
+		if f.Message != "" {
+			return nil, errors.New(f.Message)
+		}
+
+		return nil, fmt.Errorf("value should match %s", f.Pattern)
+	}
+
+	return raw, nil
+}
+
+func (f Field) inRange(v float64, name string) error {
+	switch {
+	case f.Min != nil && v < *f.Min:
+		return fmt.Errorf("%s should be at least %s", name, formatFloat(*f.Min))
+	case f.Max != nil && v > *f.Max:
+		return fmt.Errorf("%s should be at most %s", name, formatFloat(*f.Max))
+	}
+
+	return nil
+}
+
+// Defaults returns the default options of select fields.
+func (f Field) Defaults() []string {
+	if f.Default == "" {
+		return nil
+	}
+
+	if f.Type != TypeMultiSelect {
+		return []string{f.Default}
+	}
+
+	var v []string
+
+	for _, s := range strings.Split(f.Default, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			v = append(v, s)
+		}
+	}
+
+	return v
+}
+
+func (f Field) hasOption(option string) bool {
+	for _, o := range f.Options {
+		if o == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate checks the fields and compiles the patterns.
+func (a *Action) Validate() error {
+	var issues check.Issues
+
+	if len(a.Fields) == 0 {
+		issues.Addf("fields", "at least one field is required")
+	}
+
+	names := make(map[string]struct{}, len(a.Fields))
+
+	for i := range a.Fields {
+		f := &a.Fields[i]
+		path := check.Index("fields", i)
+
+		switch _, ok := names[f.Name]; {
+		case f.Name == "":
+			issues.Addf(check.Join(path, "name"), "name is required")
+		case !namePattern.MatchString(f.Name):
+			issues.Addf(check.Join(path, "name"), "name should have only letters, digits and underscores")
+		case ok:
+			issues.Addf(check.Join(path, "name"), "duplicated field name %q", f.Name)
+		}
+
+		names[f.Name] = struct{}{}
+
+		if f.Type == "" {
+			f.Type = TypeText
+		}
+
+		issues.Add(path, f.validate())
+	}
+
+	if a.Confirm != nil {
+		issues.Add("confirm", a.Confirm.Validate())
+	}
+
+	return issues.Err()
+}
+
+func (f *Field) validate() error {
+	var issues check.Issues
+
+	switch f.Type {
+	case TypeText, TypePassword, TypeNumber, TypeMultiLine, TypeCheckbox:
+		if len(f.Options) > 0 {
+			issues.Addf("options", "options are used with select and multi_select")
+		}
+	case TypeSelect, TypeMultiSelect:
+		if len(f.Options) == 0 {
+			issues.Addf("options", "options are required")
+		}
+
+		for _, d := range f.Defaults() {
+			if !f.hasOption(d) {
+				issues.Addf("default", "default %q is not an option", d)
+			}
+		}
+	default:
+		issues.Addf("type", "unknown type %q, use text, password, number, select, multi_select, checkbox or multi_line", f.Type)
+	}
+
+	if f.Pattern != "" {
+		if !f.IsText() || f.Type == TypeNumber {
+			issues.Addf("pattern", "pattern is used with text, password and multi_line")
+		}
+
+		p, err := regexp.Compile(f.Pattern)
+		if err != nil {
+			issues.Addf("pattern", "invalid pattern: %v", err)
+		}
+
+		f.pattern = p
+	}
+
+	if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+		issues.Addf("min", "min should not be greater than max")
+	}
+
+	if f.Rows < 0 {
+		issues.Addf("rows", "rows should be positive")
+	}
+
+	if f.Rows == 0 {
+		f.Rows = 4
+	}
+
+	if f.Type == TypeCheckbox && f.Default != "" {
+		if _, err := strconv.ParseBool(f.Default); err != nil {
+			issues.Addf("default", "default should be true or false")
+		}
+	}
+
+	if f.IsText() && f.Default != "" {
+		// required is checked on submit, an empty default is allowed
+		if _, err := f.Parse(f.Default); err != nil {
+			issues.Addf("default", "invalid default: %v", err)
+		}
+	}
+
+	return issues.Err()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,116 @@
+package form
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestFieldParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   Field
+		raw     string
+		want    interface{}
+		wantErr string
+	}{
+		{name: "text", field: Field{Type: TypeText}, raw: " web-1 ", want: "web-1"},
+		{name: "empty", field: Field{Type: TypeText}, raw: "  ", want: nil},
+		{name: "required", field: Field{Type: TypeText, Required: true}, raw: " ", wantErr: ErrRequired.Error()},
+		{name: "multi line keeps spaces", field: Field{Type: TypeMultiLine}, raw: " a\n b \n", want: " a\n b \n"},
+		{name: "blank multi line", field: Field{Type: TypeMultiLine}, raw: " \n ", want: nil},
+		{name: "blank multi line is required", field: Field{Type: TypeMultiLine, Required: true}, raw: " \n ", wantErr: ErrRequired.Error()},
+		{name: "number", field: Field{Type: TypeNumber}, raw: " 1.5 ", want: 1.5},
+		{name: "negative number", field: Field{Type: TypeNumber}, raw: "-3", want: -3.0},
+		{name: "not a number", field: Field{Type: TypeNumber}, raw: "ten", wantErr: `"ten" is not a number`},
+		{name: "number below min", field: Field{Type: TypeNumber, Min: float(1)}, raw: "0.5", wantErr: "value should be at least 1"},
+		{name: "number above max", field: Field{Type: TypeNumber, Max: float(2.5)}, raw: "3", wantErr: "value should be at most 2.5"},
+		{name: "number on the limits", field: Field{Type: TypeNumber, Min: float(1), Max: float(3)}, raw: "3", want: 3.0},
+		{name: "short text", field: Field{Type: TypeText, Min: float(3)}, raw: "ab", wantErr: "length should be at least 3"},
+		{name: "long text", field: Field{Type: TypePassword, Max: float(3)}, raw: "abcd", wantErr: "length should be at most 3"},
+		{name: "length in runes", field: Field{Type: TypeText, Max: float(3)}, raw: "çöü", want: "çöü"},
+		{name: "pattern", field: Field{Type: TypeText, Pattern: `^[a-z]+-\d+$`}, raw: "web-1", want: "web-1"},
+		{name: "pattern mismatch", field: Field{Type: TypeText, Pattern: `^[a-z]+$`}, raw: "web-1", wantErr: "value should match ^[a-z]+$"},
+		{name: "pattern message", field: Field{Type: TypeText, Pattern: `^[a-z]+$`, Message: "letters only"}, raw: "1", wantErr: "letters only"},
+		{name: "empty value skips the pattern", field: Field{Type: TypeText, Pattern: `^[a-z]+$`}, raw: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Action{Fields: []Field{tt.field}}
+			a.Fields[0].Name = "field"
+
+			if err := a.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			got, err := a.Fields[0].Parse(tt.raw)
+
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Parse() error = %v, want %s", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldParseRequired(t *testing.T) {
+	f := Field{Type: TypeNumber, Required: true}
+
+	if _, err := f.Parse(""); !errors.Is(err, ErrRequired) {
+		t.Errorf("Parse() error = %v, want %v", err, ErrRequired)
+	}
+}
+
+func TestActionValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []Field
+		want   string
+	}{
+		{
+			name:   "valid",
+			fields: []Field{{Name: "host"}, {Name: "env", Type: TypeMultiSelect, Options: []string{"dev", "prod"}, Default: "dev, prod"}},
+		},
+		{name: "no fields", want: "fields: at least one field is required"},
+		{name: "name", fields: []Field{{Name: "1host"}}, want: "fields[0].name: name should have only letters, digits and underscores"},
+		{name: "duplicated", fields: []Field{{Name: "host"}, {Name: "host"}}, want: `fields[1].name: duplicated field name "host"`},
+		{name: "type", fields: []Field{{Name: "host", Type: "date"}}, want: `fields[0].type: unknown type "date", use text, password, number, select, multi_select, checkbox or multi_line`},
+		{name: "select without options", fields: []Field{{Name: "env", Type: TypeSelect}}, want: "fields[0].options: options are required"},
+		{name: "default not an option", fields: []Field{{Name: "env", Type: TypeSelect, Options: []string{"dev"}, Default: "prod"}}, want: `fields[0].default: default "prod" is not an option`},
+		{name: "pattern of a number", fields: []Field{{Name: "port", Type: TypeNumber, Pattern: `\d+`}}, want: "fields[0].pattern: pattern is used with text, password and multi_line"},
+		{name: "min greater than max", fields: []Field{{Name: "port", Type: TypeNumber, Min: float(2), Max: float(1)}}, want: "fields[0].min: min should not be greater than max"},
+		{name: "checkbox default", fields: []Field{{Name: "ok", Type: TypeCheckbox, Default: "yes"}}, want: "fields[0].default: default should be true or false"},
+		{name: "invalid default", fields: []Field{{Name: "port", Type: TypeNumber, Default: "x"}}, want: `fields[0].default: invalid default: "x" is not a number`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Action{Fields: tt.fields}
+
+			got := ""
+			if err := a.Validate(); err != nil {
+				got = err.Error()
+			}
+
+			if got != tt.want {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
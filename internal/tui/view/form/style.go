@@ -0,0 +1,42 @@
+package form
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rytsh/yap/internal/tui/style"
+)
+
+// styles of the view, built from the theme.
+type styles struct {
+	*style.Styles
+
+	box          lipgloss.Style
+	banner       lipgloss.Style
+	label        lipgloss.Style
+	focusedLabel lipgloss.Style
+	help         lipgloss.Style
+	fieldError   lipgloss.Style
+}
+
+func newStyles(s *style.Styles) styles {
+	return styles{
+		Styles: s,
+
+		box: lipgloss.NewStyle().
+			Border(s.Border).
+			BorderForeground(s.Highlight).
+			Padding(0, 1),
+
+		banner: lipgloss.NewStyle().
+			Border(s.BannerBorder).
+			BorderTop(true).
+			BorderBottom(true).
+			BorderLeft(false).
+			BorderRight(false).
+			Padding(0, 1),
+
+		label:        lipgloss.NewStyle().Bold(true),
+		focusedLabel: s.Focused.Copy().Bold(true),
+		help:         s.Blurred.Copy(),
+		fieldError:   s.Fail.Copy(),
+	}
+}
@@ -0,0 +1,743 @@
+package form
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/style"
+	"github.com/rytsh/yap/internal/tui/view/confirm"
+)
+
+type FormModel struct {
+	width  int
+	height int
+	keymap keymapForm
+	help   help.Model
+	styles styles
+
+	index   model.Index
+	session *model.Session
+
+	action Action
+	inputs []*input
+	// focus is the index of the focused input, buttons are after the inputs.
+	focus int
+
+	// dialog is open until the pending values are confirmed.
+	dialog        *confirm.Dialog
+	pending       map[string]interface{}
+	pendingParams map[string]interface{}
+}
+
+type keymapForm = struct {
+	next, prev, up, down, toggle, submit, back, quit key.Binding
+}
+
+// input is the state of a field.
+type input struct {
+	field Field
+
+	text textinput.Model
+	area textarea.Model
+	// cursor is the highlighted option, selected option of select fields.
+	cursor   int
+	selected map[int]bool
+	checked  bool
+
+	err error
+}
+
+func NewFormModel(action Action) *FormModel {
+	m := FormModel{
+		action: action,
+		styles: newStyles(style.DefaultStyles()),
+		help:   help.New(),
+		keymap: keymapForm{
+			next: key.NewBinding(
+				key.WithKeys("tab"),
+				key.WithHelp("tab", "next"),
+			),
+			prev: key.NewBinding(
+				key.WithKeys("shift+tab"),
+				key.WithHelp("shift+tab", "prev"),
+			),
+			up: key.NewBinding(
+				key.WithKeys("up"),
+				key.WithHelp("↑", "up"),
+			),
+			down: key.NewBinding(
+				key.WithKeys("down"),
+				key.WithHelp("↓", "down"),
+			),
+			toggle: key.NewBinding(
+				key.WithKeys(" "),
+				key.WithHelp("space", "toggle"),
+			),
+			submit: key.NewBinding(
+				key.WithKeys("enter", "ctrl+s"),
+				key.WithHelp("enter/ctrl+s", "submit"),
+			),
+			back: key.NewBinding(
+				key.WithKeys("esc"),
+				key.WithHelp("esc", "back"),
+			),
+			quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "quit"),
+			),
+		},
+	}
+
+	return &m
+}
+
+func (m *FormModel) SetIndex(index model.Index) {
+	m.index = index
+}
+
+// Initialize fills the inputs with the values of the session, defaults are
+// used for the new values.
+func (m *FormModel) Initialize(cfg model.Config) tea.Cmd {
+	m.width = cfg.Width
+	m.height = cfg.Height
+	m.session = cfg.Session
+
+	if cfg.Styles != nil {
+		m.styles = newStyles(cfg.Styles)
+	}
+
+	values := m.session.Form()
+
+	m.inputs = make([]*input, 0, len(m.action.Fields))
+	for _, f := range m.action.Fields {
+		in := m.newInput(f)
+
+		if v, ok := values[f.Name]; ok {
+			in.setValue(v)
+		}
+
+		m.inputs = append(m.inputs, in)
+	}
+
+	m.focus = 0
+	m.resize()
+
+	return tea.Batch(textinput.Blink, m.updateFocus())
+}
+
+func (m *FormModel) newInput(f Field) *input {
+	in := &input{field: f, selected: make(map[int]bool)}
+
+	switch f.Type {
+	case TypeText, TypePassword, TypeNumber:
+		in.text = textinput.New()
+		in.text.Placeholder = f.Placeholder
+		in.text.SetValue(f.Default)
+
+		if f.Type == TypePassword {
+			in.text.EchoMode = textinput.EchoPassword
+			in.text.EchoCharacter = '*'
+		}
+	case TypeMultiLine:
+		in.area = textarea.New()
+		in.area.Placeholder = f.Placeholder
+		in.area.ShowLineNumbers = false
+		in.area.Prompt = "│ "
+		in.area.SetHeight(f.Rows)
+		in.area.SetValue(f.Default)
+		in.area.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	case TypeCheckbox:
+		in.checked, _ = strconv.ParseBool(f.Default)
+	case TypeSelect, TypeMultiSelect:
+		for _, d := range f.Defaults() {
+			for i, o := range f.Options {
+				if o == d {
+					in.selected[i] = true
+					in.cursor = i
+				}
+			}
+		}
+	}
+
+	return in
+}
+
+// setValue sets the value from the session.
+func (in *input) setValue(v interface{}) {
+	switch in.field.Type {
+	case TypeText, TypePassword, TypeNumber:
+		in.text.SetValue(model.FormatValue(v))
+	case TypeMultiLine:
+		in.area.SetValue(model.FormatValue(v))
+	case TypeCheckbox:
+		in.checked, _ = v.(bool)
+	case TypeSelect, TypeMultiSelect:
+		values, ok := v.([]string)
+		if !ok {
+			values = []string{model.FormatValue(v)}
+		}
+
+		in.selected = make(map[int]bool)
+
+		for _, value := range values {
+			for i, o := range in.field.Options {
+				if o == value {
+					in.selected[i] = true
+					in.cursor = i
+				}
+			}
+		}
+	}
+}
+
+// value returns the checked value of the input.
+func (in *input) value() (interface{}, error) {
+	f := in.field
+
+	switch f.Type {
+	case TypeText, TypePassword, TypeNumber:
+		return f.Parse(in.text.Value())
+	case TypeMultiLine:
+		return f.Parse(in.area.Value())
+	case TypeCheckbox:
+		if f.Required && !in.checked {
+			return nil, ErrRequired
+		}
+
+		return in.checked, nil
+	case TypeSelect:
+		for i, o := range f.Options {
+			if in.selected[i] {
+				return o, nil
+			}
+		}
+	case TypeMultiSelect:
+		var values []string
+
+		for i, o := range f.Options {
+			if in.selected[i] {
+				values = append(values, o)
+			}
+		}
+
+		if len(values) > 0 {
+			return values, nil
+		}
+	}
+
+	if f.Required {
+		return nil, ErrRequired
+	}
+
+	return nil, nil
+}
+
+func (m *FormModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *FormModel) config() model.Config {
+	return model.Config{
+		Width:  m.width,
+		Height: m.height,
+	}
+}
+
+// focused returns the focused input, nil on the buttons.
+func (m *FormModel) focused() *input {
+	if m.focus < len(m.inputs) {
+		return m.inputs[m.focus]
+	}
+
+	return nil
+}
+
+func (m *FormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return m.updateKey(msg)
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.resize()
+
+		return m, nil
+	}
+
+	if m.dialog != nil {
+		_, cmd := m.dialog.Update(msg)
+		return m, cmd
+	}
+
+	return m, m.updateInput(msg)
+}
+
+func (m *FormModel) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	in := m.focused()
+
+	switch {
+	case key.Matches(msg, m.keymap.quit):
+		return m, tea.Quit
+	case m.dialog != nil:
+		return m.confirm(msg)
+	case key.Matches(msg, m.keymap.back):
+		return m.index.PrevModel(m.config())
+	case key.Matches(msg, m.keymap.next):
+		return m, m.move(1)
+	case key.Matches(msg, m.keymap.prev):
+		return m, m.move(-1)
+	}
+
+	if in == nil {
+		// buttons
+		switch {
+		case key.Matches(msg, m.keymap.submit), key.Matches(msg, m.keymap.toggle):
+			if m.focus == len(m.inputs)+1 {
+				return m.index.PrevModel(m.config())
+			}
+
+			return m.submit()
+		case key.Matches(msg, m.keymap.up):
+			return m, m.move(-1)
+		case key.Matches(msg, m.keymap.down):
+			return m, m.move(1)
+		}
+
+		return m, nil
+	}
+
+	switch in.field.Type {
+	case TypeMultiLine:
+		// enter is a new line, arrows move in the text
+		if msg.String() == "ctrl+s" {
+			return m.submit()
+		}
+
+		return m, m.updateInput(msg)
+	case TypeSelect, TypeMultiSelect:
+		switch {
+		case key.Matches(msg, m.keymap.up):
+			if in.cursor > 0 {
+				in.cursor--
+				m.choose(in)
+			}
+
+			return m, nil
+		case key.Matches(msg, m.keymap.down):
+			if in.cursor < len(in.field.Options)-1 {
+				in.cursor++
+				m.choose(in)
+			}
+
+			return m, nil
+		case key.Matches(msg, m.keymap.toggle):
+			if in.field.Type == TypeMultiSelect {
+				in.selected[in.cursor] = !in.selected[in.cursor]
+			} else {
+				in.selected = map[int]bool{in.cursor: true}
+			}
+
+			in.err = nil
+
+			return m, nil
+		}
+	case TypeCheckbox:
+		if key.Matches(msg, m.keymap.toggle) {
+			in.checked = !in.checked
+			in.err = nil
+
+			return m, nil
+		}
+	}
+
+	switch {
+	case key.Matches(msg, m.keymap.submit):
+		return m.submit()
+	case key.Matches(msg, m.keymap.up):
+		return m, m.move(-1)
+	case key.Matches(msg, m.keymap.down):
+		return m, m.move(1)
+	}
+
+	return m, m.updateInput(msg)
+}
+
+// choose selects the option under the cursor of select fields.
+func (m *FormModel) choose(in *input) {
+	if in.field.Type == TypeSelect {
+		in.selected = map[int]bool{in.cursor: true}
+		in.err = nil
+	}
+}
+
+func (m *FormModel) updateInput(msg tea.Msg) tea.Cmd {
+	in := m.focused()
+	if in == nil {
+		return nil
+	}
+
+	var cmd tea.Cmd
+
+	switch in.field.Type {
+	case TypeText, TypePassword, TypeNumber:
+		in.text, cmd = in.text.Update(msg)
+	case TypeMultiLine:
+		in.area, cmd = in.area.Update(msg)
+	default:
+		return nil
+	}
+
+	if _, ok := msg.(tea.KeyMsg); ok {
+		in.err = nil
+	}
+
+	return cmd
+}
+
+// move changes the focus, inputs and the two buttons are in a loop.
+func (m *FormModel) move(step int) tea.Cmd {
+	count := len(m.inputs) + 2
+	m.focus = (m.focus + step + count) % count
+
+	return m.updateFocus()
+}
+
+func (m *FormModel) updateFocus() tea.Cmd {
+	var cmds []tea.Cmd
+
+	for i, in := range m.inputs {
+		focused := i == m.focus
+
+		switch in.field.Type {
+		case TypeText, TypePassword, TypeNumber:
+			if focused {
+				cmds = append(cmds, in.text.Focus())
+				in.text.PromptStyle = m.styles.Focused
+				in.text.TextStyle = m.styles.Focused
+
+				continue
+			}
+
+			in.text.Blur()
+			in.text.PromptStyle = m.styles.NoStyle
+			in.text.TextStyle = m.styles.NoStyle
+		case TypeMultiLine:
+			if focused {
+				cmds = append(cmds, in.area.Focus())
+				continue
+			}
+
+			in.area.Blur()
+		}
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// submit checks the values, the values are added to the session and the next
+// view is opened.
+func (m *FormModel) submit() (tea.Model, tea.Cmd) {
+	values := make(map[string]interface{}, len(m.inputs))
+	params := make(map[string]interface{}, len(m.inputs))
+	first := -1
+
+	for i, in := range m.inputs {
+		v, err := in.value()
+		in.err = err
+
+		if err != nil {
+			if first < 0 {
+				first = i
+			}
+
+			continue
+		}
+
+		if v == nil {
+			continue
+		}
+
+		values[in.field.Name] = v
+		params[in.field.Name] = v
+
+		if in.field.Type == TypePassword {
+			params[in.field.Name] = "***"
+		}
+	}
+
+	if first >= 0 {
+		m.focus = first
+
+		return m, m.updateFocus()
+	}
+
+	if m.action.Confirm == nil {
+		return m.save(values, params)
+	}
+
+	// message is rendered with the masked passwords like the params
+	data := m.session.MaskedTemplateData()
+	for k, v := range params {
+		data.Form[k] = model.FormatValue(v)
+	}
+
+	dialogParams := make([]confirm.Param, 0, len(m.inputs))
+	for _, in := range m.inputs {
+		if v, ok := params[in.field.Name]; ok {
+			dialogParams = append(dialogParams, confirm.Param{Name: in.field.Title(), Value: model.FormatValue(v)})
+		}
+	}
+
+	d, err := confirm.New(*m.action.Confirm, data, false, dialogParams, m.styles.Styles)
+	if err != nil {
+		m.session.Audit(audit.Event{
+			Type:   audit.TypeAction,
+			Action: "form",
+			Name:   m.action.Banner,
+			Result: audit.ResultError,
+			Error:  err.Error(),
+		})
+
+		return m, nil
+	}
+
+	m.dialog = d
+	m.pending = values
+	m.pendingParams = params
+
+	return m, d.Init()
+}
+
+// confirm sends the key to the dialog, the pending values are saved if accepted.
+func (m *FormModel) confirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	result, cmd := m.dialog.Update(msg)
+
+	switch result {
+	case confirm.Accepted:
+		m.dialog = nil
+		return m.save(m.pending, m.pendingParams)
+	case confirm.Canceled:
+		m.dialog = nil
+		m.session.Audit(audit.Event{
+			Type:   audit.TypeAction,
+			Action: "form",
+			Name:   m.action.Banner,
+			Params: m.pendingParams,
+			Result: audit.ResultCanceled,
+		})
+	}
+
+	return m, cmd
+}
+
+// save adds the values to the session and opens the next view.
+func (m *FormModel) save(values, params map[string]interface{}) (tea.Model, tea.Cmd) {
+	m.session.SetForm(values, m.secrets()...)
+	m.session.Audit(audit.Event{
+		Type:   audit.TypeAction,
+		Action: "form",
+		Name:   m.action.Banner,
+		Params: params,
+		Result: audit.ResultSuccess,
+	})
+
+	return m.index.NextModel(m.config())
+}
+
+// secrets returns the names of the password fields.
+func (m *FormModel) secrets() []string {
+	var v []string
+
+	for _, f := range m.action.Fields {
+		if f.Type == TypePassword {
+			v = append(v, f.Name)
+		}
+	}
+
+	return v
+}
+
+func (m *FormModel) innerWidth() int {
+	return style.Max(20, style.Min(70, m.width-4))
+}
+
+func (m *FormModel) resize() {
+	for _, in := range m.inputs {
+		switch in.field.Type {
+		case TypeText, TypePassword, TypeNumber:
+			in.text.Width = m.innerWidth() - 4
+		case TypeMultiLine:
+			in.area.SetWidth(m.innerWidth() - 2)
+		}
+	}
+}
+
+func (m *FormModel) inputView(i int, in *input) string {
+	f := in.field
+	focused := i == m.focus
+
+	var b strings.Builder
+
+	label := m.styles.label
+	if focused {
+		label = m.styles.focusedLabel
+	}
+
+	title := f.Title()
+	if f.Required {
+		title += " *"
+	}
+
+	if f.Type != TypeCheckbox {
+		b.WriteString(label.Render(title))
+		b.WriteRune('\n')
+	}
+
+	switch f.Type {
+	case TypeText, TypePassword, TypeNumber:
+		b.WriteString(in.text.View())
+	case TypeMultiLine:
+		b.WriteString(in.area.View())
+	case TypeCheckbox:
+		mark := "[ ] "
+		if in.checked {
+			mark = "[x] "
+		}
+
+		b.WriteString(label.Render(mark + title))
+	case TypeSelect, TypeMultiSelect:
+		options := make([]string, len(f.Options))
+
+		for j, o := range f.Options {
+			mark := "( ) "
+			if f.Type == TypeMultiSelect {
+				mark = "[ ] "
+			}
+
+			if in.selected[j] {
+				mark = "(•) "
+				if f.Type == TypeMultiSelect {
+					mark = "[x] "
+				}
+			}
+
+			option := m.styles.NoStyle
+			prefix := "  "
+
+			if focused && j == in.cursor {
+				option = m.styles.Focused
+				prefix = "> "
+			}
+
+			options[j] = option.Render(prefix + mark + o)
+		}
+
+		b.WriteString(strings.Join(options, "\n"))
+	}
+
+	switch {
+	case in.err != nil:
+		b.WriteRune('\n')
+		b.WriteString(m.styles.fieldError.Render(in.err.Error()))
+	case f.Help != "":
+		b.WriteRune('\n')
+		b.WriteString(m.styles.help.Render(f.Help))
+	}
+
+	return b.String()
+}
+
+func (m *FormModel) View() string {
+	help := m.help.ShortHelpView([]key.Binding{
+		m.keymap.next,
+		m.keymap.prev,
+		m.keymap.toggle,
+		m.keymap.submit,
+		m.keymap.back,
+		m.keymap.quit,
+	})
+
+	blocks := make([]string, len(m.inputs))
+	for i, in := range m.inputs {
+		blocks[i] = m.inputView(i, in)
+	}
+
+	submit, cancel := m.styles.Button, m.styles.Button
+	switch m.focus {
+	case len(m.inputs):
+		submit = m.styles.ActiveButton
+	case len(m.inputs) + 1:
+		cancel = m.styles.ActiveButton
+	}
+
+	label := m.action.Submit
+	if label == "" {
+		label = "Submit"
+	}
+
+	buttons := lipgloss.JoinHorizontal(lipgloss.Top,
+		submit.Copy().MarginRight(2).Render(label),
+		cancel.Render("Cancel"),
+	)
+
+	var banner string
+	if m.action.Banner != "" {
+		banner = m.styles.banner.Render(m.action.Banner)
+	}
+
+	// fields scroll to keep the focused one on the screen
+	height := m.height - lipgloss.Height(buttons) - lipgloss.Height(help) - 3
+	if banner != "" {
+		height -= lipgloss.Height(banner)
+	}
+
+	fields := m.styles.box.Width(m.innerWidth()).Render(scroll(blocks, m.focus, height))
+
+	ui := lipgloss.JoinVertical(lipgloss.Center, fields, buttons)
+	if banner != "" {
+		ui = lipgloss.JoinVertical(lipgloss.Center, banner, ui)
+	}
+
+	if m.dialog != nil {
+		return m.dialog.View(m.width, m.height)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.Place(m.width, m.height-lipgloss.Height(help)-1, lipgloss.Center, lipgloss.Center, ui),
+		help,
+	)
+}
+
+// scroll returns the blocks in the height, starting from a block that shows
+// the focused one.
+func scroll(blocks []string, focus, height int) string {
+	all := strings.Join(blocks, "\n\n")
+	if height <= 0 || lipgloss.Height(all) <= height {
+		return all
+	}
+
+	if focus >= len(blocks) {
+		focus = len(blocks) - 1
+	}
+
+	start := focus
+	for start > 0 && lipgloss.Height(strings.Join(blocks[start-1:focus+1], "\n\n")) <= height {
+		start--
+	}
+
+	lines := strings.Split(strings.Join(blocks[start:], "\n\n"), "\n")
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+
+	return strings.Join(lines, "\n")
+}
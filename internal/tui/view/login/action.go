@@ -4,16 +4,52 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/rytsh/yap/internal/check"
+	"github.com/rytsh/yap/internal/metric"
 	"github.com/rytsh/yap/internal/tui/view/login/auth"
 )
 
 var ErrLogin = errors.New("login failed")
 var ErrNoSelection = errors.New("no selection")
+var ErrBlocked = errors.New("login blocked after failed logins")
+var ErrMaxAttempts = errors.New("too many failed logins")
 
 type Action struct {
 	Banner   string `cfg:"banner"`
 	Tabs     []Auth `cfg:"tabs"`
 	Selected string `cfg:"selected"`
+	// SkipOnKey skips the login form if user authenticated with public key.
+	SkipOnKey bool `cfg:"skip_on_key"`
+}
+
+// Validate checks the tabs and prepares the auth providers.
+func (a *Action) Validate() error {
+	var issues check.Issues
+
+	if len(a.Tabs) == 0 {
+		issues.Addf("tabs", "at least one tab is required")
+	}
+
+	names := make(map[string]struct{}, len(a.Tabs))
+	for i := range a.Tabs {
+		path := check.Index("tabs", i)
+
+		if a.Tabs[i].Name == "" {
+			issues.Addf(check.Join(path, "name"), "name is required")
+		} else if _, ok := names[a.Tabs[i].Name]; ok {
+			issues.Addf(check.Join(path, "name"), "duplicated tab name %q", a.Tabs[i].Name)
+		}
+
+		names[a.Tabs[i].Name] = struct{}{}
+
+		issues.Add(path, a.Tabs[i].Prepare())
+	}
+
+	if a.Selected != "" && a.Tab(a.Selected) == nil {
+		issues.Addf("selected", "unknown tab %q", a.Selected)
+	}
+
+	return issues.Err()
 }
 
 func (a Action) GetTabNames() []string {
@@ -37,30 +73,104 @@ func (a *Action) TabSelected() string {
 	return ""
 }
 
-func (a Action) Login(selected string, username, password string) error {
+// Tab returns the tab with name, nil if not found.
+func (a Action) Tab(name string) *Auth {
+	for i := range a.Tabs {
+		if a.Tabs[i].Name == name {
+			return &a.Tabs[i]
+		}
+	}
+
+	return nil
+}
+
+// Login checks the password with the selected tab and returns the roles of the user.
+func (a Action) Login(selected string, username, password string) ([]string, error) {
 	for _, tab := range a.Tabs {
 		if tab.Name == selected {
-			return tab.Login(username, password)
+			roles, err := tab.Login(username, password)
+			metric.LoginAttempt(tab.Name, err)
+
+			return roles, err
 		}
 	}
 
-	return ErrNoSelection
+	return nil, ErrNoSelection
 }
 
 type Auth struct {
 	Name string `cfg:"name"`
 
 	BasicAuth *auth.BasicAuth `cfg:"basic_auth"`
+	OAuth2    *auth.OAuth2    `cfg:"oauth2"`
+	LDAP      *auth.LDAP      `cfg:"ldap"`
+
+	// MFA asks a TOTP code after the password login.
+	MFA *auth.MFA `cfg:"mfa"`
 }
 
-func (a Auth) Login(username, password string) error {
+// Prepare prepares the auth provider of the tab.
+func (a *Auth) Prepare() error {
+	var issues check.Issues
+
+	count := 0
+
 	if a.BasicAuth != nil {
+		count++
+		issues.Add("basic_auth", a.BasicAuth.Prepare())
+	}
+
+	if a.OAuth2 != nil {
+		count++
+		issues.Add("oauth2", a.OAuth2.Prepare())
+	}
+
+	if a.LDAP != nil {
+		count++
+		issues.Add("ldap", a.LDAP.Prepare())
+	}
+
+	switch {
+	case count == 0:
+		issues.Addf("", "an auth provider is required")
+	case count > 1:
+		issues.Addf("", "only one auth provider is allowed")
+	}
+
+	if a.MFA != nil {
+		if a.IsDevice() {
+			issues.Addf("mfa", "mfa is used with password logins")
+		} else {
+			issues.Add("mfa", a.MFA.Prepare())
+		}
+	}
+
+	return issues.Err()
+}
+
+// IsDevice returns true if tab uses device authorization instead of username and password.
+func (a Auth) IsDevice() bool {
+	return a.OAuth2 != nil
+}
+
+// Login checks the password and returns the roles of the user, LDAP groups
+// are the roles.
+func (a Auth) Login(username, password string) ([]string, error) {
+	switch {
+	case a.BasicAuth != nil:
 		if err := a.BasicAuth.Validator(username, password); err != nil {
-			return fmt.Errorf("%w: %v", ErrLogin, err)
+			return nil, fmt.Errorf("%w: %v", ErrLogin, err)
+		}
+
+		return a.BasicAuth.UserRoles(username), nil
+	case a.LDAP != nil:
+		groups, err := a.LDAP.Login(username, password)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrLogin, err)
 		}
 
-		return nil
+		return groups, nil
 	}
 
-	return ErrNoSelection
+	return nil, ErrNoSelection
 }
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	goauth "github.com/abbot/go-http-auth"
@@ -9,12 +10,28 @@ import (
 
 type BasicAuth struct {
 	Users []string `cfg:"users"`
-	Realm string   `cfg:"realm"`
+	// File is an htpasswd file, reloaded when it changes. Users are checked first.
+	File  string `cfg:"file"`
+	Realm string `cfg:"realm"`
+	// Roles are the user lists by role name.
+	Roles map[string][]string `cfg:"roles"`
 
 	gAuth *goauth.BasicAuth `cfg:"-"`
 }
 
 func (b *BasicAuth) Prepare() error {
+	if len(b.Users) == 0 && b.File == "" {
+		return fmt.Errorf("users or file is required")
+	}
+
+	var file *htpasswd
+	if b.File != "" {
+		var err error
+		if file, err = newHtpasswd(b.File); err != nil {
+			return err
+		}
+	}
+
 	v := make(map[string]string, len(b.Users))
 	for _, user := range b.Users {
 		parts := strings.Split(user, ":")
@@ -36,6 +53,10 @@ func (b *BasicAuth) Prepare() error {
 				return hash
 			}
 
+			if file != nil {
+				return file.secret(user)
+			}
+
 			return ""
 		},
 	}
@@ -54,3 +75,21 @@ func (b *BasicAuth) Validator(username, password string) error {
 
 	return nil
 }
+
+// UserRoles returns the roles of the user in name order.
+func (b *BasicAuth) UserRoles(username string) []string {
+	var roles []string
+
+	for role, users := range b.Roles {
+		for _, u := range users {
+			if u == username {
+				roles = append(roles, role)
+				break
+			}
+		}
+	}
+
+	sort.Strings(roles)
+
+	return roles
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // htpasswd SHA format
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	goauth "github.com/abbot/go-http-auth"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hash algorithms of the htpasswd entries.
+const (
+	HashBcrypt = "bcrypt"
+	HashSHA    = "sha"
+	HashMD5    = "md5"
+)
+
+// apr1Alphabet is the salt alphabet of MD5-crypt.
+const apr1Alphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// htpasswd is the users of an htpasswd file, reloaded when the file changes.
+type htpasswd struct {
+	path string
+
+	mutex   sync.Mutex
+	modTime time.Time
+	size    int64
+	users   map[string]string
+}
+
+func newHtpasswd(path string) (*htpasswd, error) {
+	h := &htpasswd{path: path}
+	if err := h.reload(); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// secret returns the hash of the user, the file is read again if it is changed.
+func (h *htpasswd) secret(user string) string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if err := h.reload(); err != nil {
+		log.Warn().Err(err).Str("file", h.path).Msg("could not reload htpasswd file")
+
+		// removed file has no users, the last users are kept for a broken file
+		// so it does not lock out everyone
+		if errors.Is(err, os.ErrNotExist) {
+			h.users = map[string]string{}
+			h.modTime = time.Time{}
+			h.size = 0
+		}
+	}
+
+	return h.users[user]
+}
+
+func (h *htpasswd) reload() error {
+	info, err := os.Stat(h.path)
+	if err != nil {
+		return fmt.Errorf("htpasswd file: %w", err)
+	}
+
+	if h.users != nil && info.ModTime().Equal(h.modTime) && info.Size() == h.size {
+		return nil
+	}
+
+	content, err := os.ReadFile(h.path)
+	if err != nil {
+		return fmt.Errorf("read htpasswd file: %w", err)
+	}
+
+	users, err := parseHtpasswd(content)
+	if err != nil {
+		return fmt.Errorf("htpasswd file %s: %w", h.path, err)
+	}
+
+	h.users = users
+	h.modTime = info.ModTime()
+	h.size = info.Size()
+
+	return nil
+}
+
+func parseHtpasswd(content []byte) (map[string]string, error) {
+	users := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		user, hash, ok := strings.Cut(text, ":")
+		if !ok || user == "" || hash == "" {
+			return nil, fmt.Errorf("line %d: expected user:hash", line)
+		}
+
+		users[user] = hash
+	}
+
+	return users, scanner.Err() //nolint:wrapcheck // no need
+}
+
+// HashPassword returns the password hash in the htpasswd format.
+func HashPassword(algorithm, password string, cost int) (string, error) {
+	switch algorithm {
+	case HashBcrypt:
+		if cost == 0 {
+			cost = bcrypt.DefaultCost
+		}
+
+		v, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+		if err != nil {
+			return "", fmt.Errorf("bcrypt: %w", err)
+		}
+
+		return string(v), nil
+	case HashSHA:
+		sum := sha1.Sum([]byte(password)) //nolint:gosec // htpasswd SHA format
+
+		return "{SHA}" + base64.StdEncoding.EncodeToString(sum[:]), nil
+	case HashMD5:
+		salt := make([]byte, 8)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("generate salt: %w", err)
+		}
+
+		for i := range salt {
+			salt[i] = apr1Alphabet[int(salt[i])%len(apr1Alphabet)]
+		}
+
+		return string(goauth.MD5Crypt([]byte(password), salt, []byte("$apr1$"))), nil
+	}
+
+	return "", fmt.Errorf("unknown algorithm %q, use bcrypt, sha or md5", algorithm)
+}
+
+// SetHtpasswd adds or replaces the user in the htpasswd file, the file is
+// created if it does not exist.
+func SetHtpasswd(path, user, hash string) error {
+	if user == "" || strings.Contains(user, ":") {
+		return fmt.Errorf("invalid user %q", user)
+	}
+
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read htpasswd file: %w", err)
+	}
+
+	entry := user + ":" + hash
+
+	var lines []string
+
+	replaced := false
+
+	if len(content) > 0 {
+		for _, line := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+			if name, _, ok := strings.Cut(strings.TrimSpace(line), ":"); ok && name == user {
+				line = entry
+				replaced = true
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	if !replaced {
+		lines = append(lines, entry)
+	}
+
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines, "\n")+"\n"), mode); err != nil {
+		return fmt.Errorf("write htpasswd file: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replace htpasswd file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	goauth "github.com/abbot/go-http-auth"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		wantErr   bool
+	}{
+		{algorithm: HashBcrypt},
+		{algorithm: HashSHA},
+		{algorithm: HashMD5},
+		{algorithm: "plain", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			hash, err := HashPassword(tt.algorithm, "secret", bcrypt.MinCost)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HashPassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !goauth.CheckSecret("secret", hash) {
+				t.Errorf("CheckSecret() of %q = false", hash)
+			}
+
+			if goauth.CheckSecret("other", hash) {
+				t.Errorf("CheckSecret() of %q with another password = true", hash)
+			}
+		})
+	}
+}
+
+func TestParseHtpasswd(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "users",
+			content: "# comment\nalice:{SHA}x\n\n  bob:$apr1$y  \n",
+			want:    map[string]string{"alice": "{SHA}x", "bob": "$apr1$y"},
+		},
+		{name: "empty", content: "", want: map[string]string{}},
+		{name: "without hash", content: "alice:\n", wantErr: true},
+		{name: "without separator", content: "alice\n", wantErr: true},
+		{name: "without user", content: ":hash\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHtpasswd([]byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHtpasswd() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseHtpasswd() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBasicAuthReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "htpasswd")
+
+	// set writes the user with a new modification time
+	modTime := time.Now().Add(-time.Hour)
+	set := func(user, password string) {
+		t.Helper()
+
+		hash, err := HashPassword(HashSHA, password, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := SetHtpasswd(path, user, hash); err != nil {
+			t.Fatalf("SetHtpasswd() error = %v", err)
+		}
+
+		modTime = modTime.Add(time.Second)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	set("alice", "first")
+
+	b := BasicAuth{Users: []string{"admin:{SHA}0DPiKuNIrrVmD8IUCuw1hQxNqZc="}, File: path}
+	if err := b.Prepare(); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	steps := []struct {
+		name string
+		// change is applied to the file before the checks
+		change func()
+		// valid and invalid are user:password pairs
+		valid   [][2]string
+		invalid [][2]string
+	}{
+		{
+			name:    "first load",
+			valid:   [][2]string{{"alice", "first"}, {"admin", "admin"}},
+			invalid: [][2]string{{"alice", "second"}, {"bob", "bob"}},
+		},
+		{
+			name:    "password is changed",
+			change:  func() { set("alice", "second") },
+			valid:   [][2]string{{"alice", "second"}},
+			invalid: [][2]string{{"alice", "first"}},
+		},
+		{
+			name:   "user is added",
+			change: func() { set("bob", "bob") },
+			valid:  [][2]string{{"alice", "second"}, {"bob", "bob"}},
+		},
+		{
+			name:    "config users are checked first",
+			change:  func() { set("admin", "other") },
+			valid:   [][2]string{{"admin", "admin"}},
+			invalid: [][2]string{{"admin", "other"}},
+		},
+		{
+			name: "broken file keeps the last users",
+			change: func() {
+				if err := os.WriteFile(path, []byte("broken\n"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			valid: [][2]string{{"alice", "second"}, {"bob", "bob"}},
+		},
+		{
+			name: "removed file has no users",
+			change: func() {
+				if err := os.Remove(path); err != nil {
+					t.Fatal(err)
+				}
+			},
+			valid:   [][2]string{{"admin", "admin"}},
+			invalid: [][2]string{{"alice", "second"}, {"bob", "bob"}},
+		},
+		{
+			name:    "file is created again",
+			change:  func() { set("carol", "carol") },
+			valid:   [][2]string{{"carol", "carol"}},
+			invalid: [][2]string{{"alice", "second"}},
+		},
+	}
+
+	for _, step := range steps {
+		if step.change != nil {
+			step.change()
+		}
+
+		for _, v := range step.valid {
+			if err := b.Validator(v[0], v[1]); err != nil {
+				t.Errorf("%s: Validator(%s, %s) error = %v", step.name, v[0], v[1], err)
+			}
+		}
+
+		for _, v := range step.invalid {
+			if err := b.Validator(v[0], v[1]); err == nil {
+				t.Errorf("%s: Validator(%s, %s) want error", step.name, v[0], v[1])
+			}
+		}
+	}
+}
+
+func TestSetHtpasswd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(path, []byte("# users\nalice:a\nbob:b\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetHtpasswd(path, "alice", "c"); err != nil {
+		t.Fatalf("SetHtpasswd() error = %v", err)
+	}
+
+	if err := SetHtpasswd(path, "carol", "d"); err != nil {
+		t.Fatalf("SetHtpasswd() error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "# users\nalice:c\nbob:b\ncarol:d\n"; string(content) != want {
+		t.Errorf("file = %q, want %q", content, want)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("file mode = %v, want 0640", info.Mode().Perm())
+	}
+
+	for _, user := range []string{"", "a:b"} {
+		if err := SetHtpasswd(path, user, "x"); err == nil {
+			t.Errorf("SetHtpasswd(%q) want error", user)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrNotInGroup         = errors.New("user is not in the required groups")
+)
+
+// LDAPConn is the LDAP connection used by the login, replaced in tests with
+// an in-process server.
+type LDAPConn interface {
+	Bind(username, password string) error
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close()
+}
+
+// LDAP binds with a service account, searches the user and binds as the user.
+type LDAP struct {
+	// URL is ldap://host:389 or ldaps://host:636.
+	URL      string `cfg:"url"`
+	StartTLS bool   `cfg:"start_tls"`
+	// InsecureSkipVerify skips the certificate check of the server.
+	InsecureSkipVerify bool          `cfg:"insecure_skip_verify"`
+	Timeout            time.Duration `cfg:"timeout"`
+
+	// BindDN and BindPassword are the service account, anonymous search if empty.
+	BindDN       string `cfg:"bind_dn"`
+	BindPassword string `cfg:"bind_password" loggable:"false"`
+
+	BaseDN string `cfg:"base_dn"`
+	// Filter finds the user, {username} is the escaped username.
+	Filter string `cfg:"filter"`
+
+	// GroupBaseDN enables the group search, memberOf of the user is used if empty.
+	GroupBaseDN string `cfg:"group_base_dn"`
+	// GroupFilter finds the groups of the user, {dn} is the escaped user DN
+	// and {username} is the escaped username.
+	GroupFilter string `cfg:"group_filter"`
+	// GroupAttribute is the group name attribute.
+	GroupAttribute string `cfg:"group_attribute"`
+	// Groups are required, user should be a member of one of them. Groups are
+	// the roles of the user, names are case sensitive like the roles.
+	Groups []string `cfg:"groups"`
+
+	// Dial opens the connection, default dials the URL.
+	Dial func() (LDAPConn, error) `cfg:"-" json:"-" loggable:"false"`
+}
+
+func (l *LDAP) Prepare() error {
+	if l.Dial == nil {
+		u, err := url.Parse(l.URL)
+		if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+			return fmt.Errorf("url should be ldap://host or ldaps://host")
+		}
+
+		if l.StartTLS && u.Scheme == "ldaps" {
+			return fmt.Errorf("start_tls is used with ldap:// url")
+		}
+	}
+
+	if l.BaseDN == "" {
+		return fmt.Errorf("base_dn is required")
+	}
+
+	if l.BindDN != "" && l.BindPassword == "" {
+		return fmt.Errorf("bind_password is required with bind_dn")
+	}
+
+	if l.Filter == "" {
+		l.Filter = "(uid={username})"
+	}
+
+	if !strings.Contains(l.Filter, "{username}") {
+		return fmt.Errorf("filter should have {username}")
+	}
+
+	if l.GroupFilter == "" {
+		l.GroupFilter = "(member={dn})"
+	}
+
+	if l.GroupAttribute == "" {
+		l.GroupAttribute = "cn"
+	}
+
+	if l.Timeout == 0 {
+		l.Timeout = 10 * time.Second
+	}
+
+	if l.Dial == nil {
+		l.Dial = l.dial
+	}
+
+	return nil
+}
+
+// Login checks the password and returns the groups of the user.
+func (l *LDAP) Login(username, password string) ([]string, error) {
+	// empty password is an unauthenticated bind, it always succeeds
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	if l.Dial == nil {
+		return nil, fmt.Errorf("ldap not prepared")
+	}
+
+	conn, err := l.Dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if l.BindDN != "" {
+		if err := conn.Bind(l.BindDN, l.BindPassword); err != nil {
+			return nil, fmt.Errorf("service bind: %w", err)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		l.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(l.Timeout/time.Second), false,
+		strings.ReplaceAll(l.Filter, "{username}", ldap.EscapeFilter(username)),
+		[]string{"dn", "memberOf"}, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("search user: %w", err)
+	}
+
+	if len(result.Entries) != 1 {
+		// unknown and ambiguous users are the same for the client
+		return nil, ErrInvalidCredentials
+	}
+
+	user := result.Entries[0]
+
+	if err := conn.Bind(user.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+
+		return nil, fmt.Errorf("user bind: %w", err)
+	}
+
+	groups, err := l.groups(conn, username, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(l.Groups) > 0 && !hasAny(groups, l.Groups) {
+		return nil, ErrNotInGroup
+	}
+
+	return groups, nil
+}
+
+// groups searches the groups or reads memberOf of the user.
+func (l *LDAP) groups(conn LDAPConn, username string, user *ldap.Entry) ([]string, error) {
+	if l.GroupBaseDN == "" {
+		var groups []string
+
+		for _, dn := range user.GetAttributeValues("memberOf") {
+			if name := rdnValue(dn, l.GroupAttribute); name != "" {
+				groups = append(groups, name)
+			}
+		}
+
+		return groups, nil
+	}
+
+	// search as the service account, users may not read the groups
+	if l.BindDN != "" {
+		if err := conn.Bind(l.BindDN, l.BindPassword); err != nil {
+			return nil, fmt.Errorf("service bind: %w", err)
+		}
+	}
+
+	filter := strings.NewReplacer(
+		"{dn}", ldap.EscapeFilter(user.DN),
+		"{username}", ldap.EscapeFilter(username),
+	).Replace(l.GroupFilter)
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		l.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(l.Timeout/time.Second), false,
+		filter, []string{l.GroupAttribute}, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("search groups: %w", err)
+	}
+
+	groups := make([]string, 0, len(result.Entries))
+	for _, e := range result.Entries {
+		if name := e.GetAttributeValue(l.GroupAttribute); name != "" {
+			groups = append(groups, name)
+		}
+	}
+
+	return groups, nil
+}
+
+func (l *LDAP) dial() (LDAPConn, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: l.InsecureSkipVerify, //nolint:gosec // user choice
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if u, err := url.Parse(l.URL); err == nil {
+		tlsConfig.ServerName = u.Hostname()
+	}
+
+	conn, err := ldap.DialURL(l.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: l.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ldap dial: %w", err)
+	}
+
+	conn.SetTimeout(l.Timeout)
+
+	if l.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+
+			return nil, fmt.Errorf("ldap start tls: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// rdnValue returns the first value of the attribute in the DN, the first
+// attribute of the DN if the attribute is not found.
+func rdnValue(dn, attribute string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 {
+		return ""
+	}
+
+	for _, rdn := range parsed.RDNs {
+		for _, a := range rdn.Attributes {
+			// attribute types are case insensitive, values are kept as is
+			if strings.EqualFold(a.Type, attribute) {
+				return a.Value
+			}
+		}
+	}
+
+	return parsed.RDNs[0].Attributes[0].Value
+}
+
+func hasAny(values, wanted []string) bool {
+	for _, v := range values {
+		for _, w := range wanted {
+			if v == w {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// fakeLDAP is a directory of the passwords by DN and the entries by base DN.
+type fakeLDAP struct {
+	passwords map[string]string
+	entries   map[string][]*ldap.Entry
+	// bindErr is returned by the binds instead of the password check.
+	bindErr error
+
+	binds   []string
+	filters []string
+	closed  bool
+}
+
+func (f *fakeLDAP) Bind(username, password string) error {
+	f.binds = append(f.binds, username)
+
+	if f.bindErr != nil {
+		return f.bindErr
+	}
+
+	if p, ok := f.passwords[username]; !ok || p != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+
+	return nil
+}
+
+func (f *fakeLDAP) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	f.filters = append(f.filters, req.Filter)
+
+	return &ldap.SearchResult{Entries: f.entries[req.BaseDN]}, nil
+}
+
+func (f *fakeLDAP) Close() {
+	f.closed = true
+}
+
+const (
+	ldapServiceDN = "cn=yap,ou=services,dc=example,dc=com"
+	ldapPeopleDN  = "ou=people,dc=example,dc=com"
+	ldapGroupsDN  = "ou=groups,dc=example,dc=com"
+	ldapAliceDN   = "uid=alice,ou=people,dc=example,dc=com"
+)
+
+func newFakeLDAP() *fakeLDAP {
+	return &fakeLDAP{
+		passwords: map[string]string{
+			ldapServiceDN: "service",
+			ldapAliceDN:   "secret",
+		},
+		entries: map[string][]*ldap.Entry{
+			ldapPeopleDN: {
+				ldap.NewEntry(ldapAliceDN, map[string][]string{
+					"memberOf": {
+						"cn=admins,ou=groups,dc=example,dc=com",
+						"ou=ops,dc=example,dc=com",
+						"not a dn",
+					},
+				}),
+			},
+			ldapGroupsDN: {
+				ldap.NewEntry("cn=yap-users,"+ldapGroupsDN, map[string][]string{"cn": {"yap-users"}}),
+				ldap.NewEntry("cn=Operators,"+ldapGroupsDN, map[string][]string{"cn": {"Operators"}}),
+				ldap.NewEntry("cn=empty,"+ldapGroupsDN, nil),
+			},
+		},
+	}
+}
+
+func TestLDAPLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      LDAP
+		username string
+		password string
+		// change is applied to the directory before the login
+		change      func(f *fakeLDAP)
+		want        []string
+		wantErr     error
+		wantAnyErr  bool
+		wantBinds   []string
+		wantFilters []string
+	}{
+		{
+			name:        "memberOf groups are the roles",
+			cfg:         LDAP{BindDN: ldapServiceDN, BindPassword: "service"},
+			username:    "alice",
+			password:    "secret",
+			want:        []string{"admins", "ops"},
+			wantBinds:   []string{ldapServiceDN, ldapAliceDN},
+			wantFilters: []string{"(uid=alice)"},
+		},
+		{
+			name:     "group search",
+			cfg:      LDAP{BindDN: ldapServiceDN, BindPassword: "service", GroupBaseDN: ldapGroupsDN},
+			username: "alice",
+			password: "secret",
+			want:     []string{"yap-users", "Operators"},
+			// groups are searched as the service account
+			wantBinds:   []string{ldapServiceDN, ldapAliceDN, ldapServiceDN},
+			wantFilters: []string{"(uid=alice)", "(member=" + ldap.EscapeFilter(ldapAliceDN) + ")"},
+		},
+		{
+			name:        "anonymous search",
+			cfg:         LDAP{},
+			username:    "alice",
+			password:    "secret",
+			want:        []string{"admins", "ops"},
+			wantBinds:   []string{ldapAliceDN},
+			wantFilters: []string{"(uid=alice)"},
+		},
+		{
+			name:        "username is escaped",
+			cfg:         LDAP{},
+			username:    "a*)(uid=*",
+			password:    "secret",
+			change:      func(f *fakeLDAP) { f.entries[ldapPeopleDN] = nil },
+			wantErr:     ErrInvalidCredentials,
+			wantFilters: []string{`(uid=a\2a\29\28uid=\2a)`},
+		},
+		{
+			name:     "empty password",
+			cfg:      LDAP{},
+			username: "alice",
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name:       "service bind failure",
+			cfg:        LDAP{BindDN: ldapServiceDN, BindPassword: "wrong"},
+			username:   "alice",
+			password:   "secret",
+			wantAnyErr: true,
+			wantBinds:  []string{ldapServiceDN},
+		},
+		{
+			name:      "wrong password",
+			cfg:       LDAP{},
+			username:  "alice",
+			password:  "wrong",
+			wantErr:   ErrInvalidCredentials,
+			wantBinds: []string{ldapAliceDN},
+		},
+		{
+			name:       "user bind failure",
+			cfg:        LDAP{},
+			username:   "alice",
+			password:   "secret",
+			change:     func(f *fakeLDAP) { f.bindErr = ldap.NewError(ldap.LDAPResultUnavailable, errors.New("down")) },
+			wantAnyErr: true,
+			wantBinds:  []string{ldapAliceDN},
+		},
+		{
+			name:     "no user",
+			cfg:      LDAP{},
+			username: "bob",
+			password: "secret",
+			change:   func(f *fakeLDAP) { f.entries[ldapPeopleDN] = nil },
+			wantErr:  ErrInvalidCredentials,
+		},
+		{
+			name:     "several users",
+			cfg:      LDAP{},
+			username: "alice",
+			password: "secret",
+			change: func(f *fakeLDAP) {
+				f.entries[ldapPeopleDN] = append(f.entries[ldapPeopleDN], ldap.NewEntry("uid=alice,ou=other,dc=example,dc=com", nil))
+			},
+			wantErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "required group",
+			cfg:      LDAP{Groups: []string{"users", "ops"}},
+			username: "alice",
+			password: "secret",
+			want:     []string{"admins", "ops"},
+		},
+		{
+			name:     "not in the required groups",
+			cfg:      LDAP{Groups: []string{"users"}},
+			username: "alice",
+			password: "secret",
+			wantErr:  ErrNotInGroup,
+		},
+		{
+			name:     "required group is case sensitive like the roles",
+			cfg:      LDAP{Groups: []string{"operators"}, GroupBaseDN: ldapGroupsDN},
+			username: "alice",
+			password: "secret",
+			wantErr:  ErrNotInGroup,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeLDAP()
+			if tt.change != nil {
+				tt.change(f)
+			}
+
+			l := tt.cfg
+			l.BaseDN = ldapPeopleDN
+			l.Dial = func() (LDAPConn, error) { return f, nil }
+
+			if err := l.Prepare(); err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+
+			got, err := l.Login(tt.username, tt.password)
+
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Login() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantAnyErr:
+				if err == nil || errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("Login() error = %v, want a server error", err)
+				}
+			case err != nil:
+				t.Errorf("Login() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Login() = %q, want %q", got, tt.want)
+			}
+
+			if tt.wantBinds != nil && !reflect.DeepEqual(f.binds, tt.wantBinds) {
+				t.Errorf("binds = %q, want %q", f.binds, tt.wantBinds)
+			}
+
+			if tt.wantFilters != nil && !reflect.DeepEqual(f.filters, tt.wantFilters) {
+				t.Errorf("filters = %q, want %q", f.filters, tt.wantFilters)
+			}
+
+			if tt.password != "" && !f.closed {
+				t.Error("connection is not closed")
+			}
+		})
+	}
+}
+
+func TestLDAPPrepare(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     LDAP
+		wantErr bool
+	}{
+		{name: "url", cfg: LDAP{URL: "ldap://localhost:389", BaseDN: ldapPeopleDN}},
+		{name: "ldaps", cfg: LDAP{URL: "ldaps://localhost", BaseDN: ldapPeopleDN}},
+		{name: "other scheme", cfg: LDAP{URL: "http://localhost", BaseDN: ldapPeopleDN}, wantErr: true},
+		{name: "start tls with ldaps", cfg: LDAP{URL: "ldaps://localhost", StartTLS: true, BaseDN: ldapPeopleDN}, wantErr: true},
+		{name: "without base dn", cfg: LDAP{URL: "ldap://localhost"}, wantErr: true},
+		{name: "bind dn without password", cfg: LDAP{URL: "ldap://localhost", BaseDN: ldapPeopleDN, BindDN: ldapServiceDN}, wantErr: true},
+		{name: "filter without username", cfg: LDAP{URL: "ldap://localhost", BaseDN: ldapPeopleDN, Filter: "(uid=x)"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Prepare(); (err != nil) != tt.wantErr {
+				t.Errorf("Prepare() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,457 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // RFC 6238 default algorithm
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidCode = errors.New("invalid code")
+	ErrNotEnrolled = errors.New("second factor is not set for the user")
+)
+
+// RecoveryCodeCount is the number of recovery codes of an enrollment.
+var RecoveryCodeCount = 8
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFA is the TOTP (RFC 6238) second factor of the password login.
+type MFA struct {
+	// Issuer is shown in the authenticator apps, "yap" if empty.
+	Issuer string `cfg:"issuer"`
+	// Digits of the code, 6 or 8.
+	Digits int           `cfg:"digits"`
+	Period time.Duration `cfg:"period"`
+	// Skew is the accepted periods before and after the current one.
+	Skew int `cfg:"skew"`
+	// Users are the secrets and the recovery codes by username.
+	Users map[string]MFAUser `cfg:"users"`
+	// Enroll shows the QR code to users without a secret.
+	Enroll bool `cfg:"enroll"`
+	// File keeps the enrolled secrets and the used codes, required to enroll
+	// and with recovery codes.
+	File string `cfg:"file"`
+
+	store *mfaStore `cfg:"-"`
+}
+
+type MFAUser struct {
+	// Secret is the base32 encoded key.
+	Secret        string   `cfg:"secret" loggable:"false"`
+	RecoveryCodes []string `cfg:"recovery_codes" loggable:"false"`
+}
+
+// Enrollment is a new secret waiting for the first code.
+type Enrollment struct {
+	Username string
+	Secret   string
+
+	issuer string
+	digits int
+	period time.Duration
+}
+
+// URI returns the otpauth URI of the authenticator apps.
+func (e *Enrollment) URI() string {
+	v := url.Values{}
+	v.Set("secret", e.Secret)
+	v.Set("issuer", e.issuer)
+	v.Set("digits", fmt.Sprint(e.digits))
+	v.Set("period", fmt.Sprint(int(e.period/time.Second)))
+
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + e.issuer + ":" + e.Username,
+		RawQuery: v.Encode(),
+	}).String()
+}
+
+func (m *MFA) Prepare() error {
+	if m.Issuer == "" {
+		m.Issuer = "yap"
+	}
+
+	if m.Digits == 0 {
+		m.Digits = 6
+	}
+
+	if m.Period == 0 {
+		m.Period = 30 * time.Second
+	}
+
+	switch {
+	case m.Digits != 6 && m.Digits != 8:
+		return fmt.Errorf("digits should be 6 or 8")
+	case m.Period < time.Second:
+		return fmt.Errorf("period should be at least 1s")
+	case m.Skew < 0:
+		return fmt.Errorf("skew should be positive")
+	case m.Enroll && m.File == "":
+		return fmt.Errorf("file is required to enroll")
+	}
+
+	for name, user := range m.Users {
+		if len(user.RecoveryCodes) > 0 && m.File == "" {
+			return fmt.Errorf("user %q: file is required to keep the used recovery codes", name)
+		}
+
+		if user.Secret == "" {
+			continue
+		}
+
+		if _, err := decodeSecret(user.Secret); err != nil {
+			return fmt.Errorf("user %q: invalid secret: %w", name, err)
+		}
+	}
+
+	store, err := loadMFAStore(m.File)
+	if err != nil {
+		return err
+	}
+
+	m.store = store
+
+	return nil
+}
+
+// Enrolled returns true if the user has a secret.
+func (m *MFA) Enrolled(username string) bool {
+	return m.secret(username) != ""
+}
+
+// Verify checks the TOTP code or a recovery code of the user, codes are
+// accepted only once.
+func (m *MFA) Verify(username, code string) error {
+	secret := m.secret(username)
+	if secret == "" {
+		return ErrNotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+	hash := hashCode(code)
+
+	codes := m.store.recoveryCodes(username)
+	for _, c := range m.Users[username].RecoveryCodes {
+		codes = append(codes, hashCode(c))
+	}
+
+	// recovery codes are checked first, a configured code may look like a TOTP code
+	for _, c := range codes {
+		if subtle.ConstantTimeCompare([]byte(c), []byte(hash)) != 1 {
+			continue
+		}
+
+		if !m.store.useRecovery(username, hash) {
+			return fmt.Errorf("%w: code is already used", ErrInvalidCode)
+		}
+
+		return nil
+	}
+
+	if len(code) != m.Digits {
+		return ErrInvalidCode
+	}
+
+	step, ok := m.match(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidCode
+	}
+
+	if !m.store.useStep(username, step) {
+		return fmt.Errorf("%w: code is already used", ErrInvalidCode)
+	}
+
+	return nil
+}
+
+// NewEnrollment generates a secret for the user.
+func (m *MFA) NewEnrollment(username string) (*Enrollment, error) {
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate secret: %w", err)
+	}
+
+	return &Enrollment{
+		Username: username,
+		Secret:   secretEncoding.EncodeToString(key),
+		issuer:   m.Issuer,
+		digits:   m.Digits,
+		period:   m.Period,
+	}, nil
+}
+
+// Complete saves the secret after the first valid code and returns new recovery codes.
+func (m *MFA) Complete(e *Enrollment, code string) ([]string, error) {
+	step, ok := m.match(e.Secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+
+	for i := range codes {
+		c, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		codes[i] = c
+		hashes[i] = hashCode(c)
+	}
+
+	if err := m.store.enroll(e.Username, e.Secret, hashes, step); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// secret returns the enrolled secret before the config secret.
+func (m *MFA) secret(username string) string {
+	if s := m.store.secret(username); s != "" {
+		return s
+	}
+
+	return m.Users[username].Secret
+}
+
+// match returns the time step of the code in the skew.
+func (m *MFA) match(secret, code string, now time.Time) (int64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	step := now.Unix() / int64(m.Period/time.Second)
+
+	for i := -m.Skew; i <= m.Skew; i++ {
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step+int64(i), m.Digits)), []byte(code)) == 1 {
+			return step + int64(i), true
+		}
+	}
+
+	return 0, false
+}
+
+// hotp returns the code of the counter (RFC 4226).
+func hotp(key []byte, counter int64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+
+	h := hmac.New(sha1.New, key)
+	h.Write(msg)
+	sum := h.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, v%mod)
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+
+	key, err := secretEncoding.DecodeString(strings.TrimRight(secret, "="))
+	if err != nil {
+		return nil, err //nolint:wrapcheck // no need
+	}
+
+	if len(key) == 0 {
+		return nil, fmt.Errorf("empty secret")
+	}
+
+	return key, nil
+}
+
+// newRecoveryCode returns a code like "abcde-fghij".
+func newRecoveryCode() (string, error) {
+	v := make([]byte, 7)
+	if _, err := rand.Read(v); err != nil {
+		return "", fmt.Errorf("generate recovery code: %w", err)
+	}
+
+	code := strings.ToLower(secretEncoding.EncodeToString(v))[:10]
+
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hashCode returns the SHA-256 of the normalized recovery code.
+func hashCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// mfaStore is the state of the users, shared by the configs with the same file.
+type mfaStore struct {
+	mutex sync.Mutex
+	file  string
+	users map[string]*mfaState
+}
+
+type mfaState struct {
+	Secret string `json:"secret,omitempty"`
+	// RecoveryCodes are the hashes of the enrolled recovery codes.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	// Used are the hashes of the used recovery codes.
+	Used []string `json:"used,omitempty"`
+	// Step is the time step of the last accepted code.
+	Step int64 `json:"step,omitempty"`
+}
+
+var (
+	mfaStoresMutex sync.Mutex
+	mfaStores      = make(map[string]*mfaStore)
+)
+
+// loadMFAStore returns the store of the file, stores without a file are in memory.
+func loadMFAStore(file string) (*mfaStore, error) {
+	mfaStoresMutex.Lock()
+	defer mfaStoresMutex.Unlock()
+
+	if s, ok := mfaStores[file]; ok {
+		return s, nil
+	}
+
+	s := &mfaStore{file: file, users: make(map[string]*mfaState)}
+
+	if file != "" {
+		content, err := os.ReadFile(file)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("read mfa file: %w", err)
+		case len(content) > 0:
+			if err := json.Unmarshal(content, &s.users); err != nil {
+				return nil, fmt.Errorf("parse mfa file %s: %w", file, err)
+			}
+		}
+	}
+
+	mfaStores[file] = s
+
+	return s, nil
+}
+
+func (s *mfaStore) state(username string) *mfaState {
+	v, ok := s.users[username]
+	if !ok {
+		v = &mfaState{}
+		s.users[username] = v
+	}
+
+	return v
+}
+
+func (s *mfaStore) secret(username string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if v, ok := s.users[username]; ok {
+		return v.Secret
+	}
+
+	return ""
+}
+
+func (s *mfaStore) recoveryCodes(username string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if v, ok := s.users[username]; ok {
+		return append([]string(nil), v.RecoveryCodes...)
+	}
+
+	return nil
+}
+
+// useStep accepts the step once, older steps are rejected.
+func (s *mfaStore) useStep(username string, step int64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	v := s.state(username)
+	if step <= v.Step {
+		return false
+	}
+
+	v.Step = step
+
+	return s.save() == nil
+}
+
+// useRecovery marks the recovery code as used.
+func (s *mfaStore) useRecovery(username, hash string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	v := s.state(username)
+	for _, used := range v.Used {
+		if used == hash {
+			return false
+		}
+	}
+
+	v.Used = append(v.Used, hash)
+
+	return s.save() == nil
+}
+
+func (s *mfaStore) enroll(username, secret string, codes []string, step int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	v := s.state(username)
+	v.Secret = secret
+	v.RecoveryCodes = codes
+	v.Used = nil
+	v.Step = step
+
+	return s.save()
+}
+
+// save writes the users to the file if it is set.
+func (s *mfaStore) save() error {
+	if s.file == "" {
+		return nil
+	}
+
+	content, err := json.Marshal(s.users)
+	if err != nil {
+		return fmt.Errorf("marshal mfa users: %w", err)
+	}
+
+	tmp := filepath.Join(filepath.Dir(s.file), "."+filepath.Base(s.file)+".tmp")
+	if err := os.WriteFile(tmp, content, 0o600); err != nil {
+		return fmt.Errorf("write mfa file: %w", err)
+	}
+
+	if err := os.Rename(tmp, s.file); err != nil {
+		return fmt.Errorf("replace mfa file: %w", err)
+	}
+
+	return nil
+}
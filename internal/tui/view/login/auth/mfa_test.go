@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the base32 of the "12345678901234567890" key of the RFCs.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 appendix D
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+
+	key, err := decodeSecret(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for counter, code := range want {
+		if got := hotp(key, int64(counter), 6); got != code {
+			t.Errorf("hotp(%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestMFAMatch(t *testing.T) {
+	// RFC 6238 appendix B, SHA1
+	tests := []struct {
+		name     string
+		time     int64
+		code     string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "59", time: 59, code: "94287082", wantStep: 1, wantOK: true},
+		{name: "1111111109", time: 1111111109, code: "07081804", wantStep: 37037036, wantOK: true},
+		{name: "1111111111", time: 1111111111, code: "14050471", wantStep: 37037037, wantOK: true},
+		{name: "1234567890", time: 1234567890, code: "89005924", wantStep: 41152263, wantOK: true},
+		{name: "2000000000", time: 2000000000, code: "69279037", wantStep: 66666666, wantOK: true},
+		{name: "previous period without skew", time: 89, code: "94287082"},
+		{name: "previous period in skew", time: 89, code: "94287082", skew: 1, wantStep: 1, wantOK: true},
+		{name: "next period in skew", time: 29, code: "94287082", skew: 1, wantStep: 1, wantOK: true},
+		{name: "wrong code", time: 59, code: "94287083", skew: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := MFA{Digits: 8, Period: 30 * time.Second, Skew: tt.skew}
+
+			step, ok := m.match(rfcSecret, tt.code, time.Unix(tt.time, 0))
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("match() = %d, %v, want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMFAPrepare(t *testing.T) {
+	tests := []struct {
+		name    string
+		mfa     MFA
+		wantErr bool
+	}{
+		{name: "defaults", mfa: MFA{}},
+		{name: "digits", mfa: MFA{Digits: 7}, wantErr: true},
+		{name: "enroll without file", mfa: MFA{Enroll: true}, wantErr: true},
+		{name: "invalid secret", mfa: MFA{Users: map[string]MFAUser{"alice": {Secret: "not base32!"}}}, wantErr: true},
+		{
+			name:    "recovery codes without file",
+			mfa:     MFA{Users: map[string]MFAUser{"alice": {Secret: rfcSecret, RecoveryCodes: []string{"abcde-fghij"}}}},
+			wantErr: true,
+		},
+		{
+			name: "recovery codes with file",
+			mfa: MFA{
+				Users: map[string]MFAUser{"alice": {Secret: rfcSecret, RecoveryCodes: []string{"abcde-fghij"}}},
+				File:  "file",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mfa.File != "" {
+				tt.mfa.File = filepath.Join(t.TempDir(), tt.mfa.File)
+			}
+
+			if err := tt.mfa.Prepare(); (err != nil) != tt.wantErr {
+				t.Errorf("Prepare() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMFAVerify(t *testing.T) {
+	key, err := decodeSecret(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step := time.Now().Unix() / 30
+	current := hotp(key, step, 6)
+	previous := hotp(key, step-1, 6)
+
+	tests := []struct {
+		name  string
+		codes []string
+		// wantErr are the errors of the codes in order, nil for accepted
+		wantErr []error
+	}{
+		{
+			name:    "code is accepted once",
+			codes:   []string{current, current},
+			wantErr: []error{nil, ErrInvalidCode},
+		},
+		{
+			name:    "older step after a newer one",
+			codes:   []string{current, previous},
+			wantErr: []error{nil, ErrInvalidCode},
+		},
+		{
+			name:    "newer step after an older one",
+			codes:   []string{previous, current},
+			wantErr: []error{nil, nil},
+		},
+		{
+			name:    "recovery code is accepted once",
+			codes:   []string{"abcde-fghij", "abcde-fghij"},
+			wantErr: []error{nil, ErrInvalidCode},
+		},
+		{
+			name:    "recovery code is normalized",
+			codes:   []string{" ABCDE FGHIJ ", "abcdefghij"},
+			wantErr: []error{nil, ErrInvalidCode},
+		},
+		{
+			name:    "recovery code with the length of a code",
+			codes:   []string{"123456", "123456"},
+			wantErr: []error{nil, ErrInvalidCode},
+		},
+		{
+			name:    "recovery code does not use the step",
+			codes:   []string{"abcde-fghij", current},
+			wantErr: []error{nil, nil},
+		},
+		{
+			name:    "unknown codes",
+			codes:   []string{"000000x", "zzzzz-zzzzz", ""},
+			wantErr: []error{ErrInvalidCode, ErrInvalidCode, ErrInvalidCode},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := MFA{
+				Skew: 1,
+				Users: map[string]MFAUser{
+					"alice": {Secret: rfcSecret, RecoveryCodes: []string{"abcde-fghij", "123456"}},
+				},
+				File: filepath.Join(t.TempDir(), "mfa.json"),
+			}
+
+			if err := m.Prepare(); err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+
+			for i, code := range tt.codes {
+				if err := m.Verify("alice", code); !errors.Is(err, tt.wantErr[i]) {
+					t.Errorf("Verify(%q) error = %v, want %v", code, err, tt.wantErr[i])
+				}
+			}
+
+			if err := m.Verify("bob", current); !errors.Is(err, ErrNotEnrolled) {
+				t.Errorf("Verify() of a user without secret error = %v, want %v", err, ErrNotEnrolled)
+			}
+		})
+	}
+}
+
+func TestMFAStoreRestart(t *testing.T) {
+	key, err := decodeSecret(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "mfa.json")
+	cfg := func() *MFA {
+		return &MFA{
+			Skew:  1,
+			Users: map[string]MFAUser{"alice": {Secret: rfcSecret, RecoveryCodes: []string{"abcde-fghij"}}},
+			File:  file,
+		}
+	}
+
+	m := cfg()
+	if err := m.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+
+	current := hotp(key, time.Now().Unix()/30, 6)
+
+	for _, code := range []string{current, "abcde-fghij"} {
+		if err := m.Verify("alice", code); err != nil {
+			t.Fatalf("Verify(%q) error = %v", code, err)
+		}
+	}
+
+	// the store is read from the file again
+	mfaStoresMutex.Lock()
+	delete(mfaStores, file)
+	mfaStoresMutex.Unlock()
+
+	m = cfg()
+	if err := m.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, code := range []string{current, "abcde-fghij"} {
+		if err := m.Verify("alice", code); !errors.Is(err, ErrInvalidCode) {
+			t.Errorf("Verify(%q) after restart error = %v, want %v", code, err, ErrInvalidCode)
+		}
+	}
+}
+
+func TestMFAEnrollment(t *testing.T) {
+	m := MFA{Enroll: true, File: filepath.Join(t.TempDir(), "mfa.json")}
+	if err := m.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Enrolled("alice") {
+		t.Fatal("Enrolled() before the enrollment")
+	}
+
+	e, err := m.NewEnrollment("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := decodeSecret(e.Secret)
+	if err != nil {
+		t.Fatalf("enrollment secret: %v", err)
+	}
+
+	if _, err := m.Complete(e, "000000x"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Complete() with a wrong code error = %v, want %v", err, ErrInvalidCode)
+	}
+
+	current := hotp(key, time.Now().Unix()/30, 6)
+
+	codes, err := m.Complete(e, current)
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	if len(codes) != RecoveryCodeCount || !m.Enrolled("alice") {
+		t.Fatalf("Complete() = %d codes, enrolled %v", len(codes), m.Enrolled("alice"))
+	}
+
+	// the code of the enrollment is used
+	if err := m.Verify("alice", current); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Verify() with the enrollment code error = %v, want %v", err, ErrInvalidCode)
+	}
+
+	if err := m.Verify("alice", codes[0]); err != nil {
+		t.Errorf("Verify() with a recovery code error = %v", err)
+	}
+}
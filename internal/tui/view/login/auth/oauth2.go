@@ -1 +1,396 @@
 package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const grantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+var (
+	ErrAuthorizationPending = errors.New("authorization pending")
+	ErrSlowDown             = errors.New("slow down")
+	ErrAccessDenied         = errors.New("access denied")
+	ErrExpiredToken         = errors.New("device code expired")
+)
+
+// OAuth2 is device authorization grant (RFC 8628) login.
+type OAuth2 struct {
+	// Issuer is the OIDC issuer, endpoints are discovered from it.
+	Issuer       string   `cfg:"issuer"`
+	ClientID     string   `cfg:"client_id"`
+	ClientSecret string   `cfg:"client_secret" loggable:"false"`
+	Scopes       []string `cfg:"scopes"`
+	// RolesClaim is the claim of the roles in the userinfo response, nested
+	// claims are separated by dots like "realm_access.roles".
+	RolesClaim string `cfg:"roles_claim"`
+	// DeviceAuthURL, TokenURL and UserInfoURL overrides discovered endpoints.
+	DeviceAuthURL string `cfg:"device_auth_url"`
+	TokenURL      string `cfg:"token_url"`
+	UserInfoURL   string `cfg:"userinfo_url"`
+
+	Client *http.Client `cfg:"-" json:"-" loggable:"false"`
+
+	mutex     sync.Mutex `cfg:"-"`
+	endpoints *endpoints `cfg:"-"`
+}
+
+// endpoints are the configured and the discovered URLs, shared by the sessions.
+type endpoints struct {
+	deviceAuth string
+	token      string
+	userInfo   string
+}
+
+func (e endpoints) complete() bool {
+	return e.deviceAuth != "" && e.token != "" && e.userInfo != ""
+}
+
+// DeviceCode is the response of the device authorization endpoint.
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURL         string `json:"verification_url"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+
+	Expiry time.Time `json:"-"`
+}
+
+// URI returns the verification URI, complete one if exist.
+func (d *DeviceCode) URI() string {
+	if d.VerificationURIComplete != "" {
+		return d.VerificationURIComplete
+	}
+
+	if d.VerificationURI != "" {
+		return d.VerificationURI
+	}
+
+	return d.VerificationURL
+}
+
+// PollInterval returns waiting duration between token requests.
+func (d *DeviceCode) PollInterval() time.Duration {
+	if d.Interval <= 0 {
+		return 5 * time.Second
+	}
+
+	return time.Duration(d.Interval) * time.Second
+}
+
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+
+	// Claims of the userinfo response of the access token.
+	Claims map[string]interface{} `json:"-"`
+}
+
+// Username returns user name from the claims.
+func (t *Token) Username() string {
+	for _, k := range []string{"preferred_username", "email", "sub"} {
+		if v, ok := t.Claims[k].(string); ok && v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// Roles returns the string values of the claim, a single string is one role.
+func (t *Token) Roles(claim string) []string {
+	if claim == "" {
+		return nil
+	}
+
+	var v interface{} = t.Claims
+	for _, k := range strings.Split(claim, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		v = m[k]
+	}
+
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		roles := make([]string, 0, len(v))
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+
+		return roles
+	}
+
+	return nil
+}
+
+type tokenError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (o *OAuth2) Prepare() error {
+	if o.ClientID == "" {
+		return fmt.Errorf("client_id is required")
+	}
+
+	if o.Issuer == "" && (o.DeviceAuthURL == "" || o.TokenURL == "" || o.UserInfoURL == "") {
+		return fmt.Errorf("issuer or device_auth_url, token_url and userinfo_url are required")
+	}
+
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return nil
+}
+
+func (o *OAuth2) client() *http.Client {
+	if o.Client == nil {
+		return http.DefaultClient
+	}
+
+	return o.Client
+}
+
+// discover returns the endpoints, missing ones are read once from the
+// issuer's openid-configuration.
+func (o *OAuth2) discover(ctx context.Context) (endpoints, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.endpoints != nil {
+		return *o.endpoints, nil
+	}
+
+	e := endpoints{
+		deviceAuth: o.DeviceAuthURL,
+		token:      o.TokenURL,
+		userInfo:   o.UserInfoURL,
+	}
+
+	if e.complete() {
+		o.endpoints = &e
+
+		return e, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(o.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return e, fmt.Errorf("discovery request: %w", err)
+	}
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return e, fmt.Errorf("discovery: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return e, fmt.Errorf("discovery: unexpected status %d", resp.StatusCode)
+	}
+
+	var v struct {
+		DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+		TokenEndpoint               string `json:"token_endpoint"`
+		UserInfoEndpoint            string `json:"userinfo_endpoint"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return e, fmt.Errorf("discovery decode: %w", err)
+	}
+
+	if e.deviceAuth == "" {
+		e.deviceAuth = v.DeviceAuthorizationEndpoint
+	}
+
+	if e.token == "" {
+		e.token = v.TokenEndpoint
+	}
+
+	if e.userInfo == "" {
+		e.userInfo = v.UserInfoEndpoint
+	}
+
+	if e.deviceAuth == "" || e.token == "" {
+		return e, fmt.Errorf("discovery: issuer does not support device authorization")
+	}
+
+	if e.userInfo == "" {
+		return e, fmt.Errorf("discovery: issuer has no userinfo endpoint")
+	}
+
+	o.endpoints = &e
+
+	return e, nil
+}
+
+func (o *OAuth2) post(ctx context.Context, endpoint string, values url.Values) (*http.Response, error) {
+	values.Set("client_id", o.ClientID)
+	if o.ClientSecret != "" {
+		values.Set("client_secret", o.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err //nolint:wrapcheck // caller wraps
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	return o.client().Do(req) //nolint:wrapcheck // caller wraps
+}
+
+// DeviceAuth starts the device authorization flow.
+func (o *OAuth2) DeviceAuth(ctx context.Context) (*DeviceCode, error) {
+	ep, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values := url.Values{}
+	if len(o.Scopes) > 0 {
+		values.Set("scope", strings.Join(o.Scopes, " "))
+	}
+
+	resp, err := o.post(ctx, ep.deviceAuth, values)
+	if err != nil {
+		return nil, fmt.Errorf("device authorization: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e tokenError
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+
+		return nil, fmt.Errorf("device authorization: status %d %s", resp.StatusCode, e.Error)
+	}
+
+	var d DeviceCode
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, fmt.Errorf("device authorization decode: %w", err)
+	}
+
+	if d.DeviceCode == "" || d.UserCode == "" {
+		return nil, fmt.Errorf("device authorization: missing device or user code")
+	}
+
+	if d.ExpiresIn > 0 {
+		d.Expiry = time.Now().Add(time.Duration(d.ExpiresIn) * time.Second)
+	}
+
+	return &d, nil
+}
+
+// Token requests the token once, returns ErrAuthorizationPending or ErrSlowDown
+// when the user is not finished yet. Claims of the token are read from the
+// userinfo endpoint.
+func (o *OAuth2) Token(ctx context.Context, d *DeviceCode) (*Token, error) {
+	if !d.Expiry.IsZero() && time.Now().After(d.Expiry) {
+		return nil, ErrExpiredToken
+	}
+
+	ep, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.post(ctx, ep.token, url.Values{
+		"grant_type":  {grantTypeDeviceCode},
+		"device_code": {d.DeviceCode},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e tokenError
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+			return nil, fmt.Errorf("token: unexpected status %d", resp.StatusCode)
+		}
+
+		switch e.Error {
+		case "authorization_pending":
+			return nil, ErrAuthorizationPending
+		case "slow_down":
+			return nil, ErrSlowDown
+		case "access_denied":
+			return nil, ErrAccessDenied
+		case "expired_token":
+			return nil, ErrExpiredToken
+		}
+
+		return nil, fmt.Errorf("token: %s %s", e.Error, e.Description)
+	}
+
+	var t Token
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return nil, fmt.Errorf("token decode: %w", err)
+	}
+
+	if t.AccessToken == "" {
+		return nil, fmt.Errorf("token: missing access_token")
+	}
+
+	claims, err := o.userInfo(ctx, ep.userInfo, t.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Claims = claims
+
+	if t.Username() == "" {
+		return nil, fmt.Errorf("userinfo: no username in the claims")
+	}
+
+	return &t, nil
+}
+
+// userInfo returns the claims of the access token from the provider, the
+// id_token is not used since its signature is not checked.
+func (o *OAuth2) userInfo(ctx context.Context, endpoint, accessToken string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("userinfo request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("userinfo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo: unexpected status %d", resp.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("userinfo decode: %w", err)
+	}
+
+	return claims, nil
+}
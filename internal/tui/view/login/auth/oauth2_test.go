@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeOIDC is an issuer with the device authorization grant, the token is
+// pending until approved is set.
+type fakeOIDC struct {
+	*httptest.Server
+
+	approved  atomic.Bool
+	discovery atomic.Int32
+	userInfo  map[string]interface{}
+}
+
+func newFakeOIDC(t *testing.T, userInfo map[string]interface{}) *fakeOIDC {
+	t.Helper()
+
+	f := &fakeOIDC{userInfo: userInfo}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		f.discovery.Add(1)
+
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                        f.URL,
+			"device_authorization_endpoint": f.URL + "/device",
+			"token_endpoint":                f.URL + "/token",
+			"userinfo_endpoint":             f.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("client_id") != "yap" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"device_code":      "device",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": f.URL + "/verify",
+			"expires_in":       600,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("grant_type") != grantTypeDeviceCode || r.PostFormValue("device_code") != "device" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		if !f.approved.Load() {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "authorization_pending"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			// unsigned, claims should not be taken from it
+			"id_token": "eyJhbGciOiJub25lIn0.eyJwcmVmZXJyZWRfdXNlcm5hbWUiOiJtYWxsb3J5In0.",
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, f.userInfo)
+	})
+
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestOAuth2DeviceFlow(t *testing.T) {
+	tests := []struct {
+		name     string
+		userInfo map[string]interface{}
+		wantUser string
+		wantErr  bool
+	}{
+		{
+			name: "preferred username and roles",
+			userInfo: map[string]interface{}{
+				"sub":                "1",
+				"preferred_username": "alice",
+				"realm_access":       map[string]interface{}{"roles": []interface{}{"admin", "dev"}},
+			},
+			wantUser: "alice",
+		},
+		{
+			name:     "subject only",
+			userInfo: map[string]interface{}{"sub": "42"},
+			wantUser: "42",
+		},
+		{
+			name:     "no username",
+			userInfo: map[string]interface{}{"name": "Alice"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeOIDC(t, tt.userInfo)
+
+			o := &OAuth2{Issuer: f.URL, ClientID: "yap", RolesClaim: "realm_access.roles"}
+			if err := o.Prepare(); err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+
+			ctx := context.Background()
+
+			code, err := o.DeviceAuth(ctx)
+			if err != nil {
+				t.Fatalf("DeviceAuth() error = %v", err)
+			}
+
+			if code.UserCode != "ABCD-EFGH" || code.URI() != f.URL+"/verify" {
+				t.Fatalf("DeviceAuth() = %+v", code)
+			}
+
+			if _, err := o.Token(ctx, code); !errors.Is(err, ErrAuthorizationPending) {
+				t.Fatalf("Token() before approval error = %v, want %v", err, ErrAuthorizationPending)
+			}
+
+			f.approved.Store(true)
+
+			token, err := o.Token(ctx, code)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Token() = %+v, want error", token)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Token() error = %v", err)
+			}
+
+			if got := token.Username(); got != tt.wantUser {
+				t.Errorf("Username() = %q, want %q", got, tt.wantUser)
+			}
+
+			if got := f.discovery.Load(); got != 1 {
+				t.Errorf("discovery requests = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestOAuth2Roles(t *testing.T) {
+	f := newFakeOIDC(t, map[string]interface{}{
+		"preferred_username": "alice",
+		"realm_access":       map[string]interface{}{"roles": []interface{}{"admin", "dev"}},
+		"group":              "ops",
+	})
+	f.approved.Store(true)
+
+	o := &OAuth2{Issuer: f.URL, ClientID: "yap"}
+
+	token, err := o.Token(context.Background(), &DeviceCode{DeviceCode: "device"})
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	tests := []struct {
+		claim string
+		want  []string
+	}{
+		{claim: "realm_access.roles", want: []string{"admin", "dev"}},
+		{claim: "group", want: []string{"ops"}},
+		{claim: "realm_access.missing", want: nil},
+		{claim: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := token.Roles(tt.claim); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Roles(%q) = %v, want %v", tt.claim, got, tt.want)
+		}
+	}
+}
+
+func TestOAuth2ConcurrentDiscovery(t *testing.T) {
+	f := newFakeOIDC(t, map[string]interface{}{"preferred_username": "alice"})
+	f.approved.Store(true)
+
+	o := &OAuth2{Issuer: f.URL, ClientID: "yap"}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			code, err := o.DeviceAuth(context.Background())
+			if err != nil {
+				t.Errorf("DeviceAuth() error = %v", err)
+				return
+			}
+
+			if _, err := o.Token(context.Background(), code); err != nil {
+				t.Errorf("Token() error = %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if got := f.discovery.Load(); got != 1 {
+		t.Errorf("discovery requests = %d, want 1", got)
+	}
+}
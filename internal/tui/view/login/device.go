@@ -0,0 +1,59 @@
+package login
+
+import (
+	"context"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rytsh/yap/internal/tui/style"
+	"github.com/rytsh/yap/internal/tui/view/login/auth"
+)
+
+// DeviceTimeout is the timeout of one request to the authorization server.
+var DeviceTimeout = 30 * time.Second
+
+// slowDownStep is added to the polling interval on slow_down response.
+const slowDownStep = 5 * time.Second
+
+// deviceMsg is the result of the device authorization request.
+type deviceMsg struct {
+	seq  int
+	code *auth.DeviceCode
+	qr   string
+	err  error
+}
+
+// tokenMsg is the result of one token request.
+type tokenMsg struct {
+	seq   int
+	token *auth.Token
+	err   error
+}
+
+func startDevice(seq int, o *auth.OAuth2) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), DeviceTimeout)
+		defer cancel()
+
+		code, err := o.DeviceAuth(ctx)
+		if err != nil {
+			return deviceMsg{seq: seq, err: err}
+		}
+
+		// QR code is a helper, show the code without it
+		qr, _ := style.QRCode(code.URI())
+
+		return deviceMsg{seq: seq, code: code, qr: qr}
+	}
+}
+
+func pollToken(seq int, o *auth.OAuth2, code *auth.DeviceCode, wait time.Duration) tea.Cmd {
+	return tea.Tick(wait, func(time.Time) tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), DeviceTimeout)
+		defer cancel()
+
+		token, err := o.Token(ctx, code)
+
+		return tokenMsg{seq: seq, token: token, err: err}
+	})
+}
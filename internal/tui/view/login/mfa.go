@@ -0,0 +1,226 @@
+package login
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/guard"
+	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/style"
+	"github.com/rytsh/yap/internal/tui/view/login/auth"
+)
+
+// MethodTOTP is the audit method of the second factor.
+const MethodTOTP = "totp"
+
+// mfaStep is the second factor after a valid password.
+type mfaStep struct {
+	username string
+	roles    []string
+	input    textinput.Model
+
+	// enrollment is set for users without a secret.
+	enrollment *auth.Enrollment
+	qr         string
+	// recovery codes are shown once after the enrollment.
+	recovery []string
+}
+
+func (m *LoginModel) startMFA(tab *Auth, username string, roles []string) (tea.Model, tea.Cmd) {
+	input := textinput.New()
+	input.CharLimit = 16
+	input.PromptStyle = m.styles.Focused
+	input.TextStyle = m.styles.Focused
+
+	step := &mfaStep{username: username, roles: roles, input: input}
+
+	if !tab.MFA.Enrolled(username) {
+		if !tab.MFA.Enroll {
+			m.err = model.TimeErr(fmt.Errorf("%w: %v", ErrLogin, auth.ErrNotEnrolled), m.time)
+			m.session.Audit(audit.Event{
+				Type:     audit.TypeAuth,
+				Identity: username,
+				Method:   MethodTOTP,
+				Tab:      m.selectedTab,
+				Result:   audit.ResultFailure,
+				Error:    auth.ErrNotEnrolled.Error(),
+			})
+
+			return m, nil
+		}
+
+		enrollment, err := tab.MFA.NewEnrollment(username)
+		if err != nil {
+			m.err = model.TimeErr(err, m.time)
+			return m, nil
+		}
+
+		step.enrollment = enrollment
+		// QR code is a helper, the secret is shown too
+		step.qr, _ = style.QRCode(enrollment.URI())
+	}
+
+	m.mfa = step
+	m.err = nil
+
+	return m, m.mfa.input.Focus()
+}
+
+func (m *LoginModel) updateMFA(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keymap.back):
+		// back to the password
+		m.mfa = nil
+		m.err = nil
+
+		return m, m.updateFocus()
+	case key.Matches(msg, m.keymap.quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keymap.login):
+		if m.mfa.recovery != nil {
+			return m.loginDone(m.mfa.username, m.mfa.roles)
+		}
+
+		return m.verifyMFA()
+	}
+
+	var cmd tea.Cmd
+	m.mfa.input, cmd = m.mfa.input.Update(msg)
+
+	return m, cmd
+}
+
+func (m *LoginModel) verifyMFA() (tea.Model, tea.Cmd) {
+	tab := m.action.Tab(m.selectedTab)
+	username := m.mfa.username
+	m.time = time.Now()
+
+	if m.blocked(username, MethodTOTP) {
+		return m, nil
+	}
+
+	code := m.mfa.input.Value()
+	m.mfa.input.Reset()
+
+	var err error
+
+	action := ""
+	if m.mfa.enrollment != nil {
+		action = "enroll"
+		m.mfa.recovery, err = tab.MFA.Complete(m.mfa.enrollment, code)
+	} else {
+		err = tab.MFA.Verify(username, code)
+	}
+
+	m.session.Audit(audit.Event{
+		Type:     audit.TypeAuth,
+		Identity: username,
+		Method:   MethodTOTP,
+		Tab:      m.selectedTab,
+		Action:   action,
+		Result:   audit.Result(err),
+		Error:    audit.ErrString(err),
+	})
+
+	if err != nil {
+		m.mfa.recovery = nil
+
+		if errors.Is(err, auth.ErrInvalidCode) {
+			return m, m.fail(username, err)
+		}
+
+		m.err = model.TimeErr(err, m.time)
+
+		return m, nil
+	}
+
+	m.err = nil
+
+	if m.mfa.enrollment != nil {
+		// recovery codes are shown before the next view
+		m.mfa.enrollment = nil
+		m.mfa.qr = ""
+		m.mfa.input.Blur()
+
+		return m, nil
+	}
+
+	return m.loginDone(username, m.mfa.roles)
+}
+
+func (m *LoginModel) mfaView() string {
+	var b strings.Builder
+
+	switch {
+	case m.mfa.recovery != nil:
+		b.WriteString("Save the recovery codes, every code works once.\n\n")
+		b.WriteString(m.styles.Focused.Render(strings.Join(m.mfa.recovery, "\n")))
+		b.WriteString("\n\nPress enter to continue.")
+
+		return b.String()
+	case m.mfa.enrollment != nil:
+		b.WriteString("Scan the code with an authenticator app\n")
+
+		if m.mfa.qr != "" {
+			b.WriteString(m.mfa.qr)
+			b.WriteRune('\n')
+		}
+
+		b.WriteString("or enter the secret\n")
+		b.WriteString(m.styles.URL.Render(m.mfa.enrollment.Secret))
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString("Authentication code\n")
+	b.WriteString(m.mfa.input.View())
+
+	return b.String()
+}
+
+// blocked checks the guard before a login attempt.
+func (m *LoginModel) blocked(username, method string) bool {
+	block := guard.Check(m.session.RemoteAddr, username)
+	if block.Remaining(m.time) == 0 {
+		return false
+	}
+
+	m.block = block
+	m.session.Audit(audit.Event{
+		Type:     audit.TypeAuth,
+		Identity: username,
+		Method:   method,
+		Tab:      m.selectedTab,
+		Result:   audit.ResultFailure,
+		Error:    ErrBlocked.Error(),
+	})
+
+	return true
+}
+
+// fail records the failed login, the session is closed after the max attempts.
+func (m *LoginModel) fail(username string, err error) tea.Cmd {
+	m.err = model.TimeErr(err, m.time)
+	m.block = guard.Fail(m.session.RemoteAddr, username)
+	m.attempts++
+
+	if maxAttempts := guard.MaxAttempts(); maxAttempts == 0 || m.attempts < maxAttempts {
+		return nil
+	}
+
+	m.closing = true
+	m.session.Audit(audit.Event{
+		Type:   audit.TypeAuth,
+		Tab:    m.selectedTab,
+		Result: audit.ResultFailure,
+		Error:  ErrMaxAttempts.Error(),
+	})
+
+	return tea.Tick(closeDelay, func(time.Time) tea.Msg { return tea.Quit() })
+}
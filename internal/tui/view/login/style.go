@@ -5,20 +5,32 @@ import (
 	"github.com/rytsh/yap/internal/tui/style"
 )
 
-var (
-	boxStyle = lipgloss.NewStyle().
-			Border(lipgloss.NormalBorder()).
-			BorderForeground(style.Highlight).
+// styles of the view, built from the theme.
+type styles struct {
+	*style.Styles
+
+	box    lipgloss.Style
+	banner lipgloss.Style
+}
+
+func newStyles(s *style.Styles) styles {
+	return styles{
+		Styles: s,
+
+		box: lipgloss.NewStyle().
+			Border(s.Border).
+			BorderForeground(s.Highlight).
 			BorderTop(false).
 			BorderLeft(true).
 			BorderRight(true).
-			BorderBottom(true)
+			BorderBottom(true),
 
-	bannerStyle = lipgloss.NewStyle().
-			Border(lipgloss.DoubleBorder()).
+		banner: lipgloss.NewStyle().
+			Border(s.BannerBorder).
 			BorderTop(true).
 			BorderBottom(true).
 			BorderLeft(false).
 			BorderRight(false).
-			Padding(0, 1)
-)
+			Padding(0, 1),
+	}
+}
@@ -1,6 +1,8 @@
 package login
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -9,8 +11,12 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/guard"
+	"github.com/rytsh/yap/internal/metric"
 	"github.com/rytsh/yap/internal/tui/model"
 	"github.com/rytsh/yap/internal/tui/style"
+	"github.com/rytsh/yap/internal/tui/view/login/auth"
 )
 
 type LoginModel struct {
@@ -24,21 +30,42 @@ type LoginModel struct {
 	focusMax   int
 	err        error
 	time       time.Time
+	session    *model.Session
+
+	// attempts is the failed login count of the session.
+	attempts int
+	block    guard.Block
+	closing  bool
+
+	mfa *mfaStep
 
 	index model.Index
 
 	action      Action
+	styles      styles
 	tabs        []string
 	selectedTab string
+
+	device     *auth.DeviceCode
+	deviceQR   string
+	deviceSeq  int
+	deviceWait time.Duration
 }
 
+// closeDelay shows the reason before the session is closed.
+const closeDelay = 2 * time.Second
+
+// skipMsg passes the login for users authenticated on the SSH layer.
+type skipMsg struct{}
+
 type keymapLogin = struct {
-	next, prev, nextTab, prevTab, login, quit, selection key.Binding
+	next, prev, nextTab, prevTab, login, quit, back, selection key.Binding
 }
 
 func NewLoginModel(action Action) *LoginModel {
 	m := LoginModel{
 		action:      action,
+		styles:      newStyles(style.DefaultStyles()),
 		tabs:        action.GetTabNames(),
 		selectedTab: action.TabSelected(),
 		inputs:      make([]textinput.Model, 2),
@@ -61,6 +88,10 @@ func NewLoginModel(action Action) *LoginModel {
 				key.WithKeys("esc", "ctrl+c"),
 				key.WithHelp("esc", "quit"),
 			),
+			back: key.NewBinding(
+				key.WithKeys("esc"),
+				key.WithHelp("esc", "back"),
+			),
 			selection: key.NewBinding(
 				key.WithKeys(" "),
 				key.WithHelp("space", "select"),
@@ -91,7 +122,7 @@ func NewLoginModel(action Action) *LoginModel {
 	}
 
 	m.focusMax = len(m.inputs) + 2
-	m.focusIndex = 0
+	m.focusIndex = m.focusMin()
 
 	return &m
 }
@@ -104,16 +135,40 @@ func (m *LoginModel) Initialize(cfg model.Config) tea.Cmd {
 	m.width = cfg.Width
 	m.height = cfg.Height
 
+	if cfg.Styles != nil {
+		m.styles = newStyles(cfg.Styles)
+	}
+
+	m.session = cfg.Session
+	if identity := m.session.Identity(); identity != "" {
+		m.inputs[0].SetValue(identity)
+		m.focusIndex = style.Max(1, m.focusMin())
+	}
+
+	m.updateFocus()
+
 	return m.Init()
 }
 
-func (m LoginModel) Init() tea.Cmd {
+func (m *LoginModel) Init() tea.Cmd {
+	if m.action.SkipOnKey && m.session.Method() == model.MethodPublicKey {
+		return func() tea.Msg { return skipMsg{} }
+	}
+
 	return tea.Batch(textinput.Blink, m.updateFocus())
 }
 
-func (m LoginModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+func (m *LoginModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.closing {
+			return m, nil
+		}
+
+		if m.mfa != nil {
+			return m.updateMFA(msg)
+		}
+
 		// fmt.Println("[" + msg.String() + "]")
 		switch {
 		case key.Matches(msg, m.keymap.quit):
@@ -124,12 +179,12 @@ func (m LoginModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keymap.next):
 			m.focusIndex++
 			if m.focusIndex > m.focusMax-1 {
-				m.focusIndex = 0
+				m.focusIndex = m.focusMin()
 			}
 			return m, m.updateFocus()
 		case key.Matches(msg, m.keymap.prev):
 			m.focusIndex--
-			if m.focusIndex < 0 {
+			if m.focusIndex < m.focusMin() {
 				m.focusIndex = m.focusMax - 1
 			}
 			return m, m.updateFocus()
@@ -147,11 +202,74 @@ func (m LoginModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case key.Matches(msg, m.keymap.nextTab):
 			m.selectedTab = style.SwitchTab(m.tabs, m.selectedTab, true)
-			return m, nil
+			return m, m.switchTab()
 		case key.Matches(msg, m.keymap.prevTab):
 			m.selectedTab = style.SwitchTab(m.tabs, m.selectedTab, false)
+			return m, m.switchTab()
+		}
+	case deviceMsg:
+		if msg.seq != m.deviceSeq {
+			return m, nil
+		}
+
+		if msg.err != nil {
+			m.err = model.TimeErr(msg.err, m.time)
+			return m, nil
+		}
+
+		m.device = msg.code
+		m.deviceQR = msg.qr
+		m.deviceWait = msg.code.PollInterval()
+
+		return m, pollToken(m.deviceSeq, m.action.Tab(m.selectedTab).OAuth2, m.device, m.deviceWait)
+	case tokenMsg:
+		if msg.seq != m.deviceSeq {
 			return m, nil
 		}
+
+		switch {
+		case errors.Is(msg.err, auth.ErrSlowDown):
+			m.deviceWait += slowDownStep
+			fallthrough
+		case errors.Is(msg.err, auth.ErrAuthorizationPending):
+			return m, pollToken(m.deviceSeq, m.action.Tab(m.selectedTab).OAuth2, m.device, m.deviceWait)
+		case msg.err != nil:
+			metric.LoginAttempt(m.selectedTab, msg.err)
+			m.session.Audit(audit.Event{
+				Type:   audit.TypeAuth,
+				Method: model.MethodOAuth2,
+				Tab:    m.selectedTab,
+				Result: audit.ResultFailure,
+				Error:  msg.err.Error(),
+			})
+			m.resetDevice()
+			m.err = model.TimeErr(fmt.Errorf("%w: %v", ErrLogin, msg.err), m.time)
+			return m, nil
+		}
+
+		metric.LoginAttempt(m.selectedTab, nil)
+		m.resetDevice()
+		m.session.SetIdentity(msg.token.Username(), model.MethodOAuth2)
+		m.session.SetTab(m.selectedTab)
+		roles := msg.token.Roles(m.action.Tab(m.selectedTab).OAuth2.RolesClaim)
+		m.session.SetRoles(roles)
+
+		event := audit.Event{Type: audit.TypeAuth, Tab: m.selectedTab, Result: audit.ResultSuccess}
+		if len(roles) > 0 {
+			event.Params = map[string]interface{}{"roles": roles}
+		}
+
+		m.session.Audit(event)
+
+		return m.index.NextModel(model.Config{
+			Width:  m.width,
+			Height: m.height,
+		})
+	case skipMsg:
+		return m.index.NextModel(model.Config{
+			Width:  m.width,
+			Height: m.height,
+		})
 	case tea.WindowSizeMsg:
 		m.height = msg.Height
 		m.width = msg.Width
@@ -165,18 +283,103 @@ func (m LoginModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 func (m *LoginModel) login() (tea.Model, tea.Cmd) {
-	if err := m.action.Login(m.selectedTab, m.inputs[0].Value(), m.inputs[1].Value()); err != nil {
-		m.err = model.TimeErr(err, m.time)
+	if tab := m.action.Tab(m.selectedTab); tab != nil && tab.IsDevice() {
+		if m.device != nil {
+			// waiting user to finish
+			return m, nil
+		}
+
+		m.err = nil
+		m.deviceSeq++
 
+		return m, startDevice(m.deviceSeq, tab.OAuth2)
+	}
+
+	username := m.inputs[0].Value()
+	m.time = time.Now()
+
+	if m.blocked(username, model.MethodPassword) {
 		return m, nil
 	}
 
+	roles, err := m.action.Login(m.selectedTab, username, m.inputs[1].Value())
+
+	var params map[string]interface{}
+	if len(roles) > 0 {
+		params = map[string]interface{}{"roles": roles}
+	}
+
+	m.session.Audit(audit.Event{
+		Type:     audit.TypeAuth,
+		Identity: username,
+		Method:   model.MethodPassword,
+		Tab:      m.selectedTab,
+		Params:   params,
+		Result:   audit.Result(err),
+		Error:    audit.ErrString(err),
+	})
+
+	if err != nil {
+		return m, m.fail(username, err)
+	}
+
+	if tab := m.action.Tab(m.selectedTab); tab != nil && tab.MFA != nil {
+		return m.startMFA(tab, username, roles)
+	}
+
+	return m.loginDone(username, roles)
+}
+
+// loginDone sets the identity and goes to the next view.
+func (m *LoginModel) loginDone(username string, roles []string) (tea.Model, tea.Cmd) {
+	guard.Success(username)
+
+	m.err = nil
+	m.mfa = nil
+	m.session.SetIdentity(username, model.MethodPassword)
+	m.session.SetTab(m.selectedTab)
+	m.session.SetRoles(roles)
+
 	return m.index.NextModel(model.Config{
 		Width:  m.width,
 		Height: m.height,
 	})
 }
 
+// isDevice returns true if selected tab uses device authorization.
+func (m *LoginModel) isDevice() bool {
+	tab := m.action.Tab(m.selectedTab)
+
+	return tab != nil && tab.IsDevice()
+}
+
+// focusMin returns first focusable index, inputs are skipped on device tabs.
+func (m *LoginModel) focusMin() int {
+	if m.isDevice() {
+		return len(m.inputs)
+	}
+
+	return 0
+}
+
+// resetDevice drops the current device flow, late messages are ignored.
+func (m *LoginModel) resetDevice() {
+	m.device = nil
+	m.deviceQR = ""
+	m.deviceSeq++
+}
+
+func (m *LoginModel) switchTab() tea.Cmd {
+	m.resetDevice()
+	m.err = nil
+
+	if m.focusIndex < m.focusMin() {
+		m.focusIndex = m.focusMin()
+	}
+
+	return m.updateFocus()
+}
+
 func (m *LoginModel) updateFocus() tea.Cmd {
 	cmds := make([]tea.Cmd, len(m.inputs))
 
@@ -185,14 +388,14 @@ func (m *LoginModel) updateFocus() tea.Cmd {
 		if i == m.focusIndex {
 			// Set focused state
 			cmds[i] = m.inputs[i].Focus()
-			m.inputs[i].PromptStyle = style.FocusedStyle
-			m.inputs[i].TextStyle = style.FocusedStyle
+			m.inputs[i].PromptStyle = m.styles.Focused
+			m.inputs[i].TextStyle = m.styles.Focused
 			continue
 		}
 		// Remove focused state
 		m.inputs[i].Blur()
-		m.inputs[i].PromptStyle = style.NoStyle
-		m.inputs[i].TextStyle = style.NoStyle
+		m.inputs[i].PromptStyle = m.styles.NoStyle
+		m.inputs[i].TextStyle = m.styles.NoStyle
 	}
 
 	return tea.Batch(cmds...)
@@ -210,7 +413,27 @@ func (m *LoginModel) updateInputs(msg tea.Msg) tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
-func (m LoginModel) View() string {
+func (m *LoginModel) deviceView() string {
+	if m.device == nil {
+		return "Submit to get a device code."
+	}
+
+	var b strings.Builder
+
+	b.WriteString("Open\n")
+	b.WriteString(m.styles.URL.Render(m.device.URI()))
+	b.WriteString("\nand enter the code\n")
+	b.WriteString(m.styles.Focused.Render(m.device.UserCode))
+
+	if m.deviceQR != "" {
+		b.WriteString("\n\n")
+		b.WriteString(m.deviceQR)
+	}
+
+	return b.String()
+}
+
+func (m *LoginModel) View() string {
 	help := m.help.ShortHelpView([]key.Binding{
 		m.keymap.next,
 		m.keymap.prev,
@@ -221,63 +444,85 @@ func (m LoginModel) View() string {
 		m.keymap.quit,
 	})
 
+	if m.mfa != nil {
+		help = m.help.ShortHelpView([]key.Binding{m.keymap.login, m.keymap.back})
+	}
+
 	var b strings.Builder
 
-	for i := range m.inputs {
-		switch i {
-		case 0:
-			b.WriteString("Username\n")
-		case 1:
-			b.WriteString("Password\n")
-		}
+	switch {
+	case m.mfa != nil:
+		b.WriteString(m.mfaView())
+	case m.isDevice():
+		b.WriteString(m.deviceView())
+	default:
+		for i := range m.inputs {
+			switch i {
+			case 0:
+				b.WriteString("Username\n")
+			case 1:
+				b.WriteString("Password\n")
+			}
 
-		b.WriteString(m.inputs[i].View())
-		if i < len(m.inputs)-1 {
-			b.WriteRune('\n')
+			b.WriteString(m.inputs[i].View())
+			if i < len(m.inputs)-1 {
+				b.WriteRune('\n')
+			}
 		}
 	}
 
 	// box shape
 	var submitButton string
 	if m.focusIndex == len(m.inputs) {
-		submitButton = style.ActiveButtonStyle.MarginRight(2).Render("Submit")
+		submitButton = m.styles.ActiveButton.MarginRight(2).Render("Submit")
 	} else {
-		submitButton = style.ButtonStyle.MarginRight(2).Render("Submit")
+		submitButton = m.styles.Button.MarginRight(2).Render("Submit")
 	}
 
 	var cancelButton string
 	if m.focusIndex == len(m.inputs)+1 {
-		cancelButton = style.ActiveButtonStyle.Render("Cancel")
+		cancelButton = m.styles.ActiveButton.Render("Cancel")
 	} else {
-		cancelButton = style.ButtonStyle.Render("Cancel")
+		cancelButton = m.styles.Button.Render("Cancel")
 	}
 
 	question := lipgloss.NewStyle().Width(m.innerWidth).Align(lipgloss.Left).Padding(0, 1).Render(b.String())
 	buttons := lipgloss.JoinHorizontal(lipgloss.Top, submitButton, cancelButton)
 
-	uiVertical := []string{question, buttons}
+	uiVertical := []string{question}
+	if m.mfa == nil {
+		uiVertical = append(uiVertical, buttons)
+	}
 
 	errStr := ""
-	if m.err != nil {
+
+	switch remaining := m.block.Remaining(m.time); {
+	case m.closing:
+		errStr = ErrMaxAttempts.Error() + ", closing the session"
+	case remaining > 0 && m.block.Locked:
+		errStr = fmt.Sprintf("locked after failed logins, try again in %s", style.Countdown(remaining))
+	case remaining > 0:
+		errStr = fmt.Sprintf("wait %s before the next login", style.Countdown(remaining))
+	case m.err != nil:
 		errStr = m.err.Error()
 	}
 
-	uiVertical = append(uiVertical, style.ErrorStyle.Width(m.innerWidth).Render(errStr))
+	uiVertical = append(uiVertical, m.styles.Error.Width(m.innerWidth).Render(errStr))
 
 	ui := lipgloss.JoinVertical(lipgloss.Center, uiVertical...)
 
-	tabs := style.Tabs(m.tabs, m.selectedTab, m.innerWidth)
+	tabs := m.styles.Tabs(m.tabs, m.selectedTab, m.innerWidth)
 
 	banner := ""
 	if m.action.Banner != "" {
-		banner = bannerStyle.Render(m.action.Banner + strings.Repeat(" ", style.Max(0, m.innerWidth-lipgloss.Width(m.action.Banner)-1)))
+		banner = m.styles.banner.Render(m.action.Banner + strings.Repeat(" ", style.Max(0, m.innerWidth-lipgloss.Width(m.action.Banner)-1)))
 	}
 
 	dialog := lipgloss.Place(m.width, 0,
 		lipgloss.Center, lipgloss.Center,
-		lipgloss.JoinVertical(lipgloss.Left, banner, tabs, boxStyle.Render(ui)),
-		lipgloss.WithWhitespaceChars("  ##  "),
-		lipgloss.WithWhitespaceForeground(style.Subtle),
+		lipgloss.JoinVertical(lipgloss.Left, banner, tabs, m.styles.box.Render(ui)),
+		lipgloss.WithWhitespaceChars(m.styles.Whitespace),
+		lipgloss.WithWhitespaceForeground(m.styles.Subtle),
 	)
 
 	return dialog + "\n\n" + help
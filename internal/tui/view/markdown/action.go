@@ -0,0 +1,107 @@
+package markdown
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rytsh/yap/internal/check"
+	"github.com/rytsh/yap/internal/tui/view/confirm"
+)
+
+// Action shows a markdown document, links like [deploy](view:deploy) go to
+// the views and code blocks like ```sh yap-run can be run in place.
+type Action struct {
+	Banner string `cfg:"banner"`
+	// File is the path of the document, it is read with the configuration so
+	// the links are checked, reload the configuration to apply the changes.
+	File string `cfg:"file"`
+	// Text is the document, used instead of a file.
+	Text string `cfg:"text"`
+	// Name is the key of the saved progress, file name or banner if empty.
+	Name string `cfg:"name"`
+	// Timeout of the runnable blocks, zero is no limit.
+	Timeout time.Duration `cfg:"timeout"`
+	// Roles are required to run the blocks, one of them is enough, the
+	// document is shown to everyone who can open the view.
+	Roles []string `cfg:"roles"`
+	// Confirm shows a dialog with the code of the block before it runs.
+	Confirm *confirm.Confirm `cfg:"confirm"`
+
+	doc document `cfg:"-"`
+}
+
+// Source reads the document.
+func (a Action) Source() (string, error) {
+	if a.File == "" {
+		return a.Text, nil
+	}
+
+	b, err := os.ReadFile(a.File)
+	if err != nil {
+		return "", fmt.Errorf("cannot read document: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// Runbook returns the name of the progress.
+func (a Action) Runbook() string {
+	switch {
+	case a.Name != "":
+		return a.Name
+	case a.File != "":
+		return strings.TrimSuffix(filepath.Base(a.File), filepath.Ext(a.File))
+	case a.Banner != "":
+		return a.Banner
+	}
+
+	return "runbook"
+}
+
+// Targets returns the view IDs of the links.
+func (a Action) Targets() []string {
+	v := make([]string, 0, len(a.doc.links))
+	for _, l := range a.doc.links {
+		v = append(v, l.target)
+	}
+
+	return v
+}
+
+// Validate reads the document, the views use the checked one.
+func (a *Action) Validate() error {
+	var issues check.Issues
+
+	switch {
+	case a.File == "" && a.Text == "":
+		issues.Addf("", "file or text is required")
+	case a.File != "" && a.Text != "":
+		issues.Addf("", "only one of file and text is allowed")
+	}
+
+	if a.Timeout < 0 {
+		issues.Addf("timeout", "timeout should be positive")
+	}
+
+	if a.Confirm != nil {
+		issues.Add("confirm", a.Confirm.Validate())
+	}
+
+	src, err := a.Source()
+	if err != nil {
+		issues.Add("file", err)
+	}
+
+	a.doc = parse(src)
+
+	for i, b := range a.doc.blocks {
+		if b.Shell() == "" {
+			issues.Addf(check.Index("blocks", i), "unsupported language %q, use sh, bash or zsh", b.lang)
+		}
+	}
+
+	return issues.Err()
+}
@@ -0,0 +1,231 @@
+package markdown
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/ansi"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// ViewScheme is the link prefix of the view IDs.
+	ViewScheme = "view:"
+	// RunTag marks the fenced code blocks that can be run, like ```sh yap-run.
+	RunTag = "yap-run"
+)
+
+var (
+	reHeading  = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)(\s+#+)?\s*$`)
+	reViewLink = regexp.MustCompile(`\[([^\]]+)\]\(` + ViewScheme + `([^)\s]+)\)`)
+	reFence    = regexp.MustCompile("^ {0,3}(```+|~~~+)\\s*(.*)$")
+	reANSI     = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+	// inline removes the inline marks of the heading titles.
+	inline = strings.NewReplacer("`", "", "**", "", "__", "")
+
+	// shells of the runnable blocks by the language of the fence.
+	shells = map[string]string{
+		"":      "sh",
+		"sh":    "sh",
+		"shell": "sh",
+		"bash":  "bash",
+		"zsh":   "zsh",
+	}
+)
+
+type heading struct {
+	level int
+	title string
+}
+
+type link struct {
+	text   string
+	target string
+}
+
+// block is a runnable code block.
+type block struct {
+	// key is the hash of the code, numbered if the same code is repeated.
+	key  string
+	lang string
+	code string
+}
+
+// Shell returns the interpreter of the block, empty if the language is not supported.
+func (b block) Shell() string {
+	return shells[b.lang]
+}
+
+// part is a markdown text or a runnable block of the document.
+type part struct {
+	text string
+	// block is the index of the runnable block, -1 for texts.
+	block int
+}
+
+// document is the outline of the markdown source.
+type document struct {
+	headings []heading
+	links    []link
+	blocks   []block
+	parts    []part
+}
+
+// parse splits the document by the runnable blocks and returns the headings
+// and the view links, fenced code is skipped.
+func parse(src string) document {
+	var (
+		doc      document
+		text     []string
+		code     []string
+		fence    string
+		runnable bool
+		lang     string
+	)
+
+	keys := make(map[string]int)
+
+	flush := func() {
+		if len(text) > 0 {
+			doc.parts = append(doc.parts, part{text: strings.Join(text, "\n"), block: -1})
+			text = nil
+		}
+	}
+
+	for _, line := range strings.Split(src, "\n") {
+		if m := reFence.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+				lang, runnable = fenceInfo(m[2])
+
+				if runnable {
+					flush()
+
+					continue
+				}
+			case strings.HasPrefix(m[1], fence) && strings.TrimSpace(m[2]) == "":
+				fence = ""
+
+				if runnable {
+					runnable = false
+
+					sum := sha256.Sum256([]byte(strings.Join(code, "\n")))
+					key := hex.EncodeToString(sum[:6])
+
+					keys[key]++
+					if keys[key] > 1 {
+						key = fmt.Sprintf("%s-%d", key, keys[key])
+					}
+
+					doc.blocks = append(doc.blocks, block{key: key, lang: lang, code: strings.Join(code, "\n")})
+					doc.parts = append(doc.parts, part{block: len(doc.blocks) - 1})
+					code = nil
+
+					continue
+				}
+			}
+
+			if runnable {
+				code = append(code, line)
+			} else {
+				text = append(text, line)
+			}
+
+			continue
+		}
+
+		if fence != "" {
+			if runnable {
+				code = append(code, line)
+			} else {
+				text = append(text, line)
+			}
+
+			continue
+		}
+
+		text = append(text, line)
+
+		if m := reHeading.FindStringSubmatch(line); m != nil {
+			doc.headings = append(doc.headings, heading{level: len(m[1]), title: inline.Replace(m[2])})
+		}
+
+		for _, m := range reViewLink.FindAllStringSubmatch(line, -1) {
+			doc.links = append(doc.links, link{text: m[1], target: m[2]})
+		}
+	}
+
+	if runnable {
+		// not closed, shown as a text
+		text = append(text, fence+lang)
+		text = append(text, code...)
+	}
+
+	flush()
+
+	return doc
+}
+
+// fenceInfo returns the language of the fence and true if it has the run tag.
+func fenceInfo(info string) (string, bool) {
+	fields := strings.Fields(info)
+
+	for i, f := range fields {
+		if f != RunTag {
+			continue
+		}
+
+		if i == 0 {
+			return "", true
+		}
+
+		return fields[0], true
+	}
+
+	return "", false
+}
+
+// renderParts renders the markdown texts in the width, blocks are empty.
+func renderParts(doc document, width int, s ansi.StyleConfig) ([]string, error) {
+	r, err := glamour.NewTermRenderer(
+		glamour.WithStyles(s),
+		glamour.WithWordWrap(width),
+		glamour.WithColorProfile(lipgloss.ColorProfile()),
+	)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // no need
+	}
+
+	v := make([]string, len(doc.parts))
+
+	for i, p := range doc.parts {
+		if p.block >= 0 {
+			continue
+		}
+
+		out, err := r.Render(p.text)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // no need
+		}
+
+		v[i] = strings.TrimRight(out, "\n")
+	}
+
+	return v, nil
+}
+
+// isHeading returns true if the rendered line ends with the title, inline
+// code has padding so the spaces are not compared.
+func isHeading(line string, h heading) bool {
+	return strings.HasSuffix(compact(reANSI.ReplaceAllString(line, "")), compact(h.title))
+}
+
+func compact(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
@@ -0,0 +1,237 @@
+package markdown
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/rytsh/yap/internal/tui/view/confirm"
+)
+
+func TestParseOutline(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		headings []heading
+		links    []link
+	}{
+		{
+			name: "headings and links",
+			src: "# Runbook\n" +
+				"Open [commands](view:commands) and [menu](view:menu).\n" +
+				"## Check `uptime` ##\n" +
+				"    #### indented code is not a heading\n" +
+				"   ### three spaces\n",
+			headings: []heading{
+				{level: 1, title: "Runbook"},
+				{level: 2, title: "Check uptime"},
+				{level: 3, title: "three spaces"},
+			},
+			links: []link{
+				{text: "commands", target: "commands"},
+				{text: "menu", target: "menu"},
+			},
+		},
+		{
+			name: "other links are not views",
+			src:  "[site](https://example.com) [mail](mailto:a@example.com) [empty](view:)\n",
+		},
+		{
+			name: "fenced code is skipped",
+			src: "```sh\n" +
+				"# comment\n" +
+				"[x](view:hidden)\n" +
+				"```\n" +
+				"## After\n",
+			headings: []heading{{level: 2, title: "After"}},
+		},
+		{
+			name: "tilde fence and longer backtick close",
+			src: "~~~\n" +
+				"# inside\n" +
+				"```\n" +
+				"# still inside\n" +
+				"~~~\n" +
+				"````md\n" +
+				"# inside\n" +
+				"`````\n" +
+				"# Outside\n",
+			headings: []heading{{level: 1, title: "Outside"}},
+		},
+		{
+			name: "fence with info does not close",
+			src: "```\n" +
+				"```sh\n" +
+				"# inside\n" +
+				"```\n" +
+				"[menu](view:menu)\n",
+			links: []link{{text: "menu", target: "menu"}},
+		},
+		{
+			name: "unclosed fence hides the rest",
+			src: "# Top\n" +
+				"```\n" +
+				"# inside\n",
+			headings: []heading{{level: 1, title: "Top"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parse(tt.src)
+
+			if !reflect.DeepEqual(doc.headings, tt.headings) {
+				t.Errorf("headings = %+v, want %+v", doc.headings, tt.headings)
+			}
+
+			if !reflect.DeepEqual(doc.links, tt.links) {
+				t.Errorf("links = %+v, want %+v", doc.links, tt.links)
+			}
+
+			if len(doc.blocks) != 0 {
+				t.Errorf("blocks = %+v, want none", doc.blocks)
+			}
+
+			// without runnable blocks the document is one text
+			if len(doc.parts) != 1 || doc.parts[0].text != tt.src {
+				t.Errorf("parts = %+v, want the source", doc.parts)
+			}
+		})
+	}
+}
+
+func codeKey(code string) string {
+	sum := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(sum[:6])
+}
+
+func TestParseBlocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		blocks []block
+		parts  []part
+		shells []string
+	}{
+		{
+			name: "runnable block splits the text",
+			src: "# Check\n" +
+				"```sh yap-run\n" +
+				"uptime\n" +
+				"# not a heading\n" +
+				"```\n" +
+				"done\n",
+			blocks: []block{{key: codeKey("uptime\n# not a heading"), lang: "sh", code: "uptime\n# not a heading"}},
+			parts: []part{
+				{text: "# Check", block: -1},
+				{block: 0},
+				{text: "done\n", block: -1},
+			},
+			shells: []string{"sh"},
+		},
+		{
+			name: "languages of the blocks",
+			src: "```yap-run\nid\n```\n" +
+				"```bash yap-run\nid -u\n```\n" +
+				"~~~python yap-run\nprint(1)\n~~~\n",
+			blocks: []block{
+				{key: codeKey("id"), code: "id"},
+				{key: codeKey("id -u"), lang: "bash", code: "id -u"},
+				{key: codeKey("print(1)"), lang: "python", code: "print(1)"},
+			},
+			parts: []part{
+				{block: 0},
+				{block: 1},
+				{block: 2},
+				{text: "", block: -1},
+			},
+			shells: []string{"sh", "bash", ""},
+		},
+		{
+			name: "repeated code gets numbered keys",
+			src: "```sh yap-run\nuptime\n```\n" +
+				"```bash yap-run\nuptime\n```\n" +
+				"```sh yap-run\nuptime\n```",
+			blocks: []block{
+				{key: codeKey("uptime"), lang: "sh", code: "uptime"},
+				{key: codeKey("uptime") + "-2", lang: "bash", code: "uptime"},
+				{key: codeKey("uptime") + "-3", lang: "sh", code: "uptime"},
+			},
+			parts:  []part{{block: 0}, {block: 1}, {block: 2}},
+			shells: []string{"sh", "bash", "sh"},
+		},
+		{
+			name: "fence without the tag is a text",
+			src:  "```sh\nuptime\n```",
+			parts: []part{
+				{text: "```sh\nuptime\n```", block: -1},
+			},
+		},
+		{
+			name: "inner fence with info is code",
+			src:  "````sh yap-run\n```sh\necho\n````",
+			blocks: []block{
+				{key: codeKey("```sh\necho"), lang: "sh", code: "```sh\necho"},
+			},
+			parts:  []part{{block: 0}},
+			shells: []string{"sh"},
+		},
+		{
+			name: "unclosed runnable block is a text",
+			src:  "intro\n```sh yap-run\nuptime\n# inside",
+			parts: []part{
+				{text: "intro", block: -1},
+				{text: "```sh\nuptime\n# inside", block: -1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parse(tt.src)
+
+			if !reflect.DeepEqual(doc.blocks, tt.blocks) {
+				t.Errorf("blocks = %+v, want %+v", doc.blocks, tt.blocks)
+			}
+
+			if !reflect.DeepEqual(doc.parts, tt.parts) {
+				t.Errorf("parts = %+v, want %+v", doc.parts, tt.parts)
+			}
+
+			if len(doc.headings) > 1 {
+				t.Errorf("headings = %+v, code is not a heading", doc.headings)
+			}
+
+			for i, b := range doc.blocks {
+				if i < len(tt.shells) && b.Shell() != tt.shells[i] {
+					t.Errorf("block %d shell = %q, want %q", i, b.Shell(), tt.shells[i])
+				}
+			}
+		})
+	}
+}
+
+func TestActionValidateReadsDocument(t *testing.T) {
+	a := Action{Text: "# Title\n[commands](view:commands)\n"}
+	if err := a.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if got, want := a.Targets(), []string{"commands"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Targets() = %v, want %v", got, want)
+	}
+
+	m := NewMarkdownModel(a)
+	if !reflect.DeepEqual(m.action.doc, a.doc) {
+		t.Error("model does not use the validated document")
+	}
+}
+
+func TestActionValidateConfirm(t *testing.T) {
+	a := Action{Text: "# Title\n", Confirm: &confirm.Confirm{Message: "{{ if .Form.env }}"}}
+	if err := a.Validate(); err == nil {
+		t.Error("Validate() of an invalid confirm want error")
+	}
+}
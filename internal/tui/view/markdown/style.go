@@ -0,0 +1,71 @@
+package markdown
+
+import (
+	"github.com/charmbracelet/glamour/ansi"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rytsh/yap/internal/tui/style"
+)
+
+// styles of the view, built from the theme.
+type styles struct {
+	document     lipgloss.Style
+	banner       lipgloss.Style
+	status       lipgloss.Style
+	link         lipgloss.Style
+	selectedLink lipgloss.Style
+	fail         lipgloss.Style
+	info         lipgloss.Style
+	markdown     ansi.StyleConfig
+
+	block         lipgloss.Style
+	selectedBlock lipgloss.Style
+	blockStatus   lipgloss.Style
+	output        lipgloss.Style
+	success       lipgloss.Style
+
+	// base is the theme of the dialogs.
+	base *style.Styles
+}
+
+func newStyles(s *style.Styles) styles {
+	return styles{
+		base: s,
+
+		document: lipgloss.NewStyle().
+			Border(s.Border).
+			BorderForeground(s.Highlight),
+
+		banner: lipgloss.NewStyle().
+			Border(s.BannerBorder).
+			BorderTop(true).
+			BorderBottom(true).
+			BorderLeft(false).
+			BorderRight(false).
+			Padding(0, 1),
+
+		status:       s.Blurred.Copy().PaddingLeft(1),
+		link:         s.URL.Copy(),
+		selectedLink: s.Focused.Copy().Bold(true).Underline(true),
+		fail:         s.Fail.Copy(),
+		info:         s.Blurred.Copy(),
+		markdown:     s.Markdown,
+
+		block: lipgloss.NewStyle().
+			Border(s.Border).
+			BorderForeground(s.Subtle).
+			Padding(0, 1).
+			MarginLeft(2),
+		selectedBlock: lipgloss.NewStyle().
+			Border(s.Border).
+			BorderForeground(s.Focused.GetForeground()).
+			Padding(0, 1).
+			MarginLeft(2),
+		blockStatus: s.Blurred.Copy().MarginLeft(2),
+		output: s.Blurred.Copy().
+			Border(lipgloss.NormalBorder(), false, false, false, true).
+			BorderForeground(s.Subtle).
+			PaddingLeft(1).
+			MarginLeft(3),
+		success: lipgloss.NewStyle().Foreground(s.Special),
+	}
+}
@@ -0,0 +1,830 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/hold"
+	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/style"
+	"github.com/rytsh/yap/internal/tui/view/command"
+	"github.com/rytsh/yap/internal/tui/view/confirm"
+)
+
+var (
+	// OutputLines is the number of the last output lines shown under a block.
+	OutputLines = 12
+	// SavedOutput is the size of the output end kept in the progress.
+	SavedOutput = 4 << 10
+)
+
+type MarkdownModel struct {
+	width    int
+	height   int
+	keymap   keymapMarkdown
+	help     help.Model
+	viewport viewport.Model
+	styles   styles
+	time     time.Time
+
+	index   model.Index
+	session *model.Session
+
+	action Action
+	doc    document
+	// rendered are the markdown parts in the viewport width.
+	rendered []string
+	// offsets are the line numbers of the headings, blockOffsets of the blocks.
+	offsets      []int
+	blockOffsets []int
+	// link is the selected link, -1 if none.
+	link int
+	// block is the selected runnable block, -1 if none.
+	block int
+	err   error
+	// message is a status message of the last key.
+	message string
+
+	progress hold.Progress
+	// outputs are the outputs of the blocks in this session.
+	outputs map[string]string
+	runner  *command.Runner
+	runID   int
+	// running is the index of the running block, -1 if none.
+	running int
+	started time.Time
+
+	// dialog is open until the pending block is confirmed.
+	dialog  *confirm.Dialog
+	pending int
+}
+
+type keymapMarkdown = struct {
+	nextHeading, prevHeading, nextLink, prevLink, nextBlock, prevBlock, enter, cancel, top, bottom, back, quit key.Binding
+}
+
+func NewMarkdownModel(action Action) *MarkdownModel {
+	vp := viewport.New(0, 0)
+	vp.KeyMap = viewport.KeyMap{
+		Up: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑/k", "up"),
+		),
+		Down: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓/j", "down"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown", " "),
+			key.WithHelp("pgdn", "page down"),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup"),
+			key.WithHelp("pgup", "page up"),
+		),
+		HalfPageUp: key.NewBinding(
+			key.WithKeys("ctrl+u"),
+		),
+		HalfPageDown: key.NewBinding(
+			key.WithKeys("ctrl+d"),
+		),
+	}
+
+	m := MarkdownModel{
+		action:   action,
+		styles:   newStyles(style.DefaultStyles()),
+		help:     help.New(),
+		viewport: vp,
+		link:     -1,
+		block:    -1,
+		running:  -1,
+		outputs:  make(map[string]string),
+		keymap: keymapMarkdown{
+			nextHeading: key.NewBinding(
+				key.WithKeys("n"),
+				key.WithHelp("n/p", "next/prev heading"),
+			),
+			prevHeading: key.NewBinding(
+				key.WithKeys("p"),
+			),
+			nextLink: key.NewBinding(
+				key.WithKeys("tab"),
+				key.WithHelp("tab", "links"),
+			),
+			prevLink: key.NewBinding(
+				key.WithKeys("shift+tab"),
+			),
+			nextBlock: key.NewBinding(
+				key.WithKeys("]"),
+				key.WithHelp("]/[", "next/prev block"),
+			),
+			prevBlock: key.NewBinding(
+				key.WithKeys("["),
+			),
+			enter: key.NewBinding(
+				key.WithKeys("enter"),
+				key.WithHelp("enter", "open/run"),
+			),
+			cancel: key.NewBinding(
+				key.WithKeys("ctrl+x"),
+				key.WithHelp("ctrl+x", "cancel"),
+			),
+			top: key.NewBinding(
+				key.WithKeys("home", "g"),
+			),
+			bottom: key.NewBinding(
+				key.WithKeys("end", "G"),
+			),
+			back: key.NewBinding(
+				key.WithKeys("esc"),
+				key.WithHelp("esc", "back"),
+			),
+			quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "quit"),
+			),
+		},
+	}
+
+	return &m
+}
+
+func (m *MarkdownModel) SetIndex(index model.Index) {
+	m.index = index
+}
+
+func (m *MarkdownModel) Initialize(cfg model.Config) tea.Cmd {
+	m.width = cfg.Width
+	m.height = cfg.Height
+	// index sets the session of every view
+	m.session = cfg.Session
+	m.message = ""
+
+	if cfg.Styles != nil {
+		m.styles = newStyles(cfg.Styles)
+	}
+
+	// document may be changed by a reload of the configuration
+	m.doc = m.action.doc
+	m.err = nil
+	m.dialog = nil
+
+	if m.link >= len(m.doc.links) {
+		m.link = -1
+	}
+
+	if m.block >= len(m.doc.blocks) {
+		m.block = -1
+	}
+
+	m.progress = hold.Progress{Blocks: make(map[string]hold.BlockResult)}
+
+	// progress of the anonymous sessions is not saved
+	if user := m.user(); user != "" && len(m.doc.blocks) > 0 {
+		var err error
+
+		m.progress, err = m.session.Cache.LoadProgress(user, m.action.Runbook())
+		if err != nil {
+			m.message = err.Error()
+		}
+	}
+
+	m.resize()
+	m.viewport.GotoTop()
+
+	return m.Init()
+}
+
+func (m *MarkdownModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *MarkdownModel) config() model.Config {
+	return model.Config{
+		Width:  m.width,
+		Height: m.height,
+	}
+}
+
+// user is the owner of the progress, empty if the session is anonymous. SSH
+// users are chosen by the clients, the keys own the progress of the public
+// key logins.
+func (m *MarkdownModel) user() string {
+	identity := m.session.Identity()
+
+	switch {
+	case identity == "":
+		return ""
+	case m.session.Method() == model.MethodPublicKey:
+		if m.session.Fingerprint == "" {
+			return ""
+		}
+
+		// base64 of the fingerprint without the characters of the paths
+		return "key@" + strings.NewReplacer("+", "-", "/", "_").Replace(strings.TrimPrefix(m.session.Fingerprint, "SHA256:"))
+	}
+
+	return identity
+}
+
+func (m *MarkdownModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		m.message = ""
+
+		switch {
+		case key.Matches(msg, m.keymap.quit):
+			m.close()
+			return m, tea.Quit
+		case m.dialog != nil:
+			return m.confirm(msg)
+		case key.Matches(msg, m.keymap.back):
+			if m.running >= 0 {
+				return m, nil
+			}
+			return m.index.PrevModel(m.config())
+		case key.Matches(msg, m.keymap.cancel):
+			if m.running >= 0 {
+				m.runner.Cancel()
+			}
+			return m, nil
+		case key.Matches(msg, m.keymap.nextHeading):
+			m.gotoHeading(1)
+			return m, nil
+		case key.Matches(msg, m.keymap.prevHeading):
+			m.gotoHeading(-1)
+			return m, nil
+		case key.Matches(msg, m.keymap.nextLink):
+			m.selectLink(1)
+			return m, nil
+		case key.Matches(msg, m.keymap.prevLink):
+			m.selectLink(-1)
+			return m, nil
+		case key.Matches(msg, m.keymap.nextBlock):
+			m.selectBlock(1)
+			return m, nil
+		case key.Matches(msg, m.keymap.prevBlock):
+			m.selectBlock(-1)
+			return m, nil
+		case key.Matches(msg, m.keymap.enter):
+			if m.block >= 0 {
+				return m.run()
+			}
+			return m.open()
+		case key.Matches(msg, m.keymap.top):
+			m.viewport.GotoTop()
+			return m, nil
+		case key.Matches(msg, m.keymap.bottom):
+			m.viewport.GotoBottom()
+			return m, nil
+		}
+	case command.OutputMsg:
+		if msg.ID != m.runID || m.running < 0 {
+			return m, nil
+		}
+
+		m.write(m.doc.blocks[m.running].key, msg.Data)
+		m.compose()
+
+		return m, m.runner.Wait()
+	case command.ExitMsg:
+		if msg.ID != m.runID || m.running < 0 {
+			return m, nil
+		}
+
+		m.finish(msg)
+		m.compose()
+
+		return m, nil
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.resize()
+
+		return m, nil
+	case model.TimeMsg:
+		m.time = time.Time(msg)
+
+		if m.running >= 0 {
+			// elapsed time of the running block
+			m.compose()
+		}
+	}
+
+	if m.dialog != nil {
+		_, cmd := m.dialog.Update(msg)
+		return m, cmd
+	}
+
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+
+	return m, cmd
+}
+
+// gotoHeading scrolls to the first heading after or the last heading before
+// the top line.
+func (m *MarkdownModel) gotoHeading(step int) {
+	top := m.viewport.YOffset
+
+	if step > 0 {
+		for _, offset := range m.offsets {
+			if offset > top {
+				m.viewport.SetYOffset(offset)
+				return
+			}
+		}
+
+		return
+	}
+
+	for i := len(m.offsets) - 1; i >= 0; i-- {
+		if m.offsets[i] < top {
+			m.viewport.SetYOffset(m.offsets[i])
+			return
+		}
+	}
+}
+
+// section returns the heading index of the top line, -1 before the first heading.
+func (m *MarkdownModel) section() int {
+	current := -1
+
+	for i, offset := range m.offsets {
+		if offset > m.viewport.YOffset {
+			break
+		}
+
+		current = i
+	}
+
+	return current
+}
+
+// next returns the next index in the cycle of n items and none as -1.
+func next(current, step, n int) int {
+	v := (current + step + n + 1) % (n + 1)
+	if v == n {
+		return -1
+	}
+
+	return v
+}
+
+func (m *MarkdownModel) selectLink(step int) {
+	if len(m.doc.links) == 0 {
+		return
+	}
+
+	m.link = next(m.link, step, len(m.doc.links))
+	m.block = -1
+	m.compose()
+}
+
+// selectBlock selects the next or the previous runnable block and scrolls to it.
+func (m *MarkdownModel) selectBlock(step int) {
+	if len(m.doc.blocks) == 0 {
+		return
+	}
+
+	m.block = next(m.block, step, len(m.doc.blocks))
+	m.link = -1
+	m.compose()
+
+	if m.block < 0 {
+		return
+	}
+
+	offset := m.blockOffsets[m.block]
+	if offset < m.viewport.YOffset || offset >= m.viewport.YOffset+m.viewport.Height-3 {
+		m.viewport.SetYOffset(offset)
+	}
+}
+
+// open goes to the view of the selected link.
+func (m *MarkdownModel) open() (tea.Model, tea.Cmd) {
+	if m.link < 0 {
+		return m, nil
+	}
+
+	if m.running >= 0 {
+		m.message = "a block is running"
+		return m, nil
+	}
+
+	target := m.doc.links[m.link].target
+
+	next, cmd := m.index.Goto(target, m.config())
+	if next == tea.Model(m) && !m.index.Allowed(target) {
+		m.message = fmt.Sprintf("unknown view %q", target)
+	}
+
+	return next, cmd
+}
+
+// run checks the roles and asks the confirmation of the selected block.
+func (m *MarkdownModel) run() (tea.Model, tea.Cmd) {
+	if m.running >= 0 {
+		return m, nil
+	}
+
+	b := m.doc.blocks[m.block]
+
+	if !m.session.HasRole(m.action.Roles) {
+		// roles may be changed after the view is opened
+		m.message = "running the blocks is not allowed"
+		m.session.Audit(audit.Event{
+			Type:   audit.TypeAction,
+			Action: "runbook",
+			Name:   m.action.Runbook(),
+			Params: map[string]interface{}{"block": b.key},
+			Result: audit.ResultFailure,
+			Error:  model.ErrDenied.Error(),
+		})
+
+		return m, nil
+	}
+
+	if m.action.Confirm == nil {
+		return m.start(m.block)
+	}
+
+	params := []confirm.Param{{Name: "block", Value: b.key}, {Name: b.Shell(), Value: b.code}}
+
+	d, err := confirm.New(*m.action.Confirm, m.session.MaskedTemplateData(), false, params, m.styles.base)
+	if err != nil {
+		m.message = err.Error()
+		m.session.Audit(audit.Event{
+			Type:   audit.TypeAction,
+			Action: "runbook",
+			Name:   m.action.Runbook(),
+			Params: map[string]interface{}{"block": b.key},
+			Result: audit.ResultError,
+			Error:  err.Error(),
+		})
+
+		return m, nil
+	}
+
+	m.dialog = d
+	m.pending = m.block
+
+	return m, d.Init()
+}
+
+// confirm sends the key to the dialog, the pending block starts if accepted.
+func (m *MarkdownModel) confirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	result, cmd := m.dialog.Update(msg)
+
+	switch result {
+	case confirm.Accepted:
+		m.dialog = nil
+		return m.start(m.pending)
+	case confirm.Canceled:
+		m.dialog = nil
+		m.session.Audit(audit.Event{
+			Type:   audit.TypeAction,
+			Action: "runbook",
+			Name:   m.action.Runbook(),
+			Params: map[string]interface{}{"block": m.doc.blocks[m.pending].key},
+			Result: audit.ResultCanceled,
+		})
+	}
+
+	return m, cmd
+}
+
+// start runs the block.
+func (m *MarkdownModel) start(i int) (tea.Model, tea.Cmd) {
+	b := m.doc.blocks[i]
+
+	m.close()
+	m.runID++
+	m.running = i
+	m.started = time.Now()
+	m.outputs[b.key] = ""
+
+	m.runner = command.Start(m.session.Context(), m.runID, command.Command{
+		Name:    m.action.Runbook(),
+		Args:    []string{b.Shell(), "-c", b.code},
+		Env:     command.FormEnv(m.session.Form()),
+		Timeout: m.action.Timeout,
+	})
+
+	m.session.Audit(audit.Event{
+		Type:   audit.TypeAction,
+		Action: "runbook",
+		Name:   m.action.Runbook(),
+		Params: map[string]interface{}{"block": b.key, "code": b.code},
+		Result: audit.ResultStarted,
+	})
+
+	m.compose()
+
+	return m, m.runner.Wait()
+}
+
+// finish saves the result of the running block.
+func (m *MarkdownModel) finish(msg command.ExitMsg) {
+	b := m.doc.blocks[m.running]
+	m.running = -1
+
+	output := m.outputs[b.key]
+	if len(output) > SavedOutput {
+		output = output[len(output)-SavedOutput:]
+	}
+
+	r := hold.BlockResult{
+		Code:     msg.Code,
+		Error:    audit.ErrString(msg.Err),
+		Output:   output,
+		Time:     time.Now(),
+		Duration: msg.Duration.Seconds(),
+	}
+
+	m.progress.Blocks[b.key] = r
+	m.progress.Updated = r.Time
+
+	result := audit.ResultSuccess
+	switch {
+	case msg.Err != nil:
+		result = audit.ResultError
+	case msg.Code != 0:
+		result = audit.ResultFailure
+	}
+
+	m.session.Audit(audit.Event{
+		Type:     audit.TypeAction,
+		Action:   "runbook",
+		Name:     m.action.Runbook(),
+		Params:   map[string]interface{}{"block": b.key, "exit_code": msg.Code},
+		Result:   result,
+		Error:    r.Error,
+		Duration: r.Duration,
+	})
+
+	user := m.user()
+	if user == "" {
+		return
+	}
+
+	// results of the other sessions of the user are shown too
+	progress, err := m.session.Cache.SaveProgress(user, m.action.Runbook(), m.progress)
+	if err != nil {
+		m.message = err.Error()
+	}
+
+	m.progress = progress
+}
+
+// write adds the data to the output of the block, older parts are dropped.
+func (m *MarkdownModel) write(key, data string) {
+	output := m.outputs[key] + data
+	if len(output) > command.MaxOutput {
+		output = output[len(output)-command.MaxOutput:]
+	}
+
+	m.outputs[key] = output
+}
+
+// close kills the running block.
+func (m *MarkdownModel) close() {
+	if m.runner != nil {
+		m.runner.Close()
+	}
+}
+
+func (m *MarkdownModel) resize() {
+	height := m.height - 5
+	if m.action.Banner != "" {
+		height -= 2
+	}
+
+	if len(m.doc.links) > 0 {
+		height--
+	}
+
+	if m.session.Identity() != "" {
+		height--
+	}
+
+	m.viewport.Width = style.Max(10, m.width-2)
+	m.viewport.Height = style.Max(3, height)
+
+	m.renderDocument()
+}
+
+// renderDocument renders the markdown parts in the width of the viewport.
+func (m *MarkdownModel) renderDocument() {
+	m.rendered = nil
+
+	if m.err == nil {
+		m.rendered, m.err = renderParts(m.doc, m.viewport.Width-2, m.styles.markdown)
+	}
+
+	m.compose()
+}
+
+// compose joins the rendered parts and the blocks, line numbers of the
+// headings and the blocks are found on the way.
+func (m *MarkdownModel) compose() {
+	if m.err != nil {
+		m.viewport.SetContent(m.styles.fail.Render(m.err.Error()))
+		m.offsets = nil
+		m.blockOffsets = make([]int, len(m.doc.blocks))
+
+		return
+	}
+
+	var lines []string
+
+	m.offsets = make([]int, len(m.doc.headings))
+	m.blockOffsets = make([]int, len(m.doc.blocks))
+
+	// headings are in order, search after the previous one
+	h := 0
+
+	for i, p := range m.doc.parts {
+		if p.block >= 0 {
+			m.blockOffsets[p.block] = len(lines)
+			lines = append(lines, "")
+			lines = append(lines, strings.Split(m.blockView(p.block), "\n")...)
+
+			continue
+		}
+
+		for _, line := range strings.Split(m.rendered[i], "\n") {
+			if h < len(m.doc.headings) && isHeading(line, m.doc.headings[h]) {
+				m.offsets[h] = len(lines)
+				h++
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	for ; h < len(m.doc.headings); h++ {
+		m.offsets[h] = len(lines)
+	}
+
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// blockView renders the code, the state and the output of the block.
+func (m *MarkdownModel) blockView(i int) string {
+	b := m.doc.blocks[i]
+
+	box := m.styles.block
+	if i == m.block {
+		box = m.styles.selectedBlock
+	}
+
+	width := style.Max(10, m.viewport.Width-8)
+	code := box.Width(width).Render(b.code)
+
+	output, ok := m.outputs[b.key]
+	result, saved := m.progress.Blocks[b.key]
+
+	var state string
+
+	switch {
+	case i == m.running:
+		elapsed := m.time.Sub(m.started)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+
+		state = m.styles.blockStatus.Render(fmt.Sprintf("… running · %s · ctrl+x cancel", elapsed.Truncate(time.Second)))
+	case saved:
+		text := fmt.Sprintf("exit %d · %s · %s", result.Code,
+			(time.Duration(result.Duration * float64(time.Second))).Truncate(time.Millisecond),
+			result.Time.Local().Format("2006-01-02 15:04"))
+		if result.Error != "" {
+			text += " · " + result.Error
+		}
+
+		if result.Success() {
+			state = m.styles.blockStatus.Render(m.styles.success.Render("✓ ") + text)
+		} else {
+			state = m.styles.blockStatus.Render(m.styles.fail.Render("✗ " + text))
+		}
+
+		if !ok {
+			output = result.Output
+		}
+	default:
+		state = m.styles.blockStatus.Render(fmt.Sprintf("○ %s · not run", b.Shell()))
+	}
+
+	v := []string{code, state}
+
+	if output = strings.TrimRight(output, "\n"); output != "" {
+		lines := strings.Split(output, "\n")
+		if len(lines) > OutputLines {
+			lines = lines[len(lines)-OutputLines:]
+		}
+
+		v = append(v, m.styles.output.Width(width).Render(strings.Join(lines, "\n")))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, v...)
+}
+
+// done returns the number of the successful blocks.
+func (m *MarkdownModel) done() int {
+	n := 0
+
+	for _, b := range m.doc.blocks {
+		if r, ok := m.progress.Blocks[b.key]; ok && r.Success() {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (m *MarkdownModel) status() string {
+	if m.message != "" {
+		return m.styles.fail.Render(m.message)
+	}
+
+	var parts []string
+
+	if i := m.section(); i >= 0 {
+		parts = append(parts, m.doc.headings[i].title)
+	}
+
+	if len(m.doc.blocks) > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d blocks done", m.done(), len(m.doc.blocks)))
+	}
+
+	parts = append(parts, fmt.Sprintf("%3.f%%", m.viewport.ScrollPercent()*100))
+
+	return m.styles.status.Render(strings.Join(parts, " · "))
+}
+
+func (m *MarkdownModel) linksView() string {
+	links := make([]string, len(m.doc.links))
+	for i, l := range m.doc.links {
+		if i == m.link {
+			links[i] = m.styles.selectedLink.Render(l.text)
+			continue
+		}
+
+		links[i] = m.styles.link.Render(l.text)
+	}
+
+	return m.styles.status.Render("links: ") + strings.Join(links, " · ")
+}
+
+func (m *MarkdownModel) View() string {
+	keys := []key.Binding{
+		m.viewport.KeyMap.Up,
+		m.viewport.KeyMap.Down,
+		m.viewport.KeyMap.PageUp,
+		m.viewport.KeyMap.PageDown,
+		m.keymap.nextHeading,
+	}
+
+	if len(m.doc.links) > 0 {
+		keys = append(keys, m.keymap.nextLink)
+	}
+
+	if len(m.doc.blocks) > 0 {
+		keys = append(keys, m.keymap.nextBlock)
+	}
+
+	help := m.help.ShortHelpView(append(keys, m.keymap.enter, m.keymap.back, m.keymap.quit))
+
+	blocks := make([]string, 0, 6)
+
+	if m.action.Banner != "" {
+		blocks = append(blocks, m.styles.banner.Render(m.action.Banner))
+	}
+
+	blocks = append(blocks, m.styles.document.Render(m.viewport.View()), m.status())
+
+	if len(m.doc.links) > 0 {
+		blocks = append(blocks, m.linksView())
+	}
+
+	if identity := m.session.Identity(); identity != "" {
+		blocks = append(blocks, m.styles.info.Render("logged in as "+identity))
+	}
+
+	ui := lipgloss.JoinVertical(lipgloss.Left, blocks...) + "\n\n" + help
+
+	if m.dialog != nil {
+		return m.dialog.View(style.Max(lipgloss.Width(ui), m.width), style.Max(lipgloss.Height(ui), m.height))
+	}
+
+	return ui
+}
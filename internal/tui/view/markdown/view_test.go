@@ -0,0 +1,134 @@
+package markdown
+
+import (
+	"os"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rytsh/yap/internal/hold"
+	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/style"
+	"github.com/rytsh/yap/internal/tui/view/command"
+	"github.com/rytsh/yap/internal/tui/view/confirm"
+)
+
+func TestRunBlock(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   []string
+		confirm *confirm.Confirm
+		// keys are sent to the dialog
+		keys        []string
+		wantDialog  bool
+		wantRunning bool
+	}{
+		{name: "no role"},
+		{name: "role without confirm", roles: []string{"ops"}, wantRunning: true},
+		{name: "confirm is open", roles: []string{"ops"}, confirm: &confirm.Confirm{}, wantDialog: true},
+		{name: "confirm is accepted", roles: []string{"ops"}, confirm: &confirm.Confirm{}, keys: []string{"y"}, wantRunning: true},
+		{name: "confirm is canceled", roles: []string{"ops"}, confirm: &confirm.Confirm{}, keys: []string{"n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Action{Text: "# Check\n```sh yap-run\ntrue\n```\n", Roles: []string{"ops", "admin"}, Confirm: tt.confirm}
+			if err := a.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			session := model.NewSession("1", "alice", "127.0.0.1:2222")
+			session.SetRoles(tt.roles)
+			defer session.Close()
+
+			m := NewMarkdownModel(a)
+			m.Initialize(model.Config{Width: 80, Height: 24, Session: session, Styles: style.DefaultStyles()})
+			defer m.close()
+
+			m.selectBlock(1)
+			m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+			for _, k := range tt.keys {
+				m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+			}
+
+			if (m.dialog != nil) != tt.wantDialog {
+				t.Errorf("dialog = %v, want %v", m.dialog != nil, tt.wantDialog)
+			}
+
+			if (m.running >= 0) != tt.wantRunning {
+				t.Errorf("running = %d, want running %v", m.running, tt.wantRunning)
+			}
+		})
+	}
+}
+
+func TestProgressUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		identity    string
+		method      string
+		fingerprint string
+		want        string
+	}{
+		{name: "anonymous", want: ""},
+		{name: "password", identity: "alice", method: model.MethodPassword, want: "alice"},
+		{
+			name:        "public key",
+			identity:    "alice",
+			method:      model.MethodPublicKey,
+			fingerprint: "SHA256:ab+c/d",
+			want:        "key@ab-c_d",
+		},
+		{name: "public key without fingerprint", identity: "alice", method: model.MethodPublicKey, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// SSH user is chosen by the client
+			session := model.NewSession("1", "bob", "127.0.0.1:2222")
+			session.SetIdentity(tt.identity, tt.method)
+			session.Fingerprint = tt.fingerprint
+
+			m := NewMarkdownModel(Action{})
+			m.session = session
+
+			if got := m.user(); got != tt.want {
+				t.Errorf("user() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgressAnonymous(t *testing.T) {
+	cache := hold.Cache{Path: t.TempDir()}
+
+	a := Action{Text: "```sh yap-run\ntrue\n```\n"}
+	if err := a.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	for _, identity := range []string{"", "alice"} {
+		session := model.NewSession("1", "alice", "127.0.0.1:2222")
+		session.Cache = cache
+
+		if identity != "" {
+			session.SetIdentity(identity, model.MethodPassword)
+		}
+
+		m := NewMarkdownModel(a)
+		m.Initialize(model.Config{Width: 80, Height: 24, Session: session, Styles: style.DefaultStyles()})
+
+		m.running = 0
+		m.finish(command.ExitMsg{})
+
+		if r, ok := m.progress.Blocks[m.doc.blocks[0].key]; !ok || !r.Success() {
+			t.Errorf("identity %q: block result is not shown", identity)
+		}
+
+		_, err := os.Stat(cache.ProgressFile("alice", a.Runbook()))
+		if saved := err == nil; saved != (identity != "") {
+			t.Errorf("identity %q: progress saved = %v", identity, saved)
+		}
+	}
+}
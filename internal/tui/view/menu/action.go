@@ -0,0 +1,137 @@
+package menu
+
+import (
+	"github.com/rytsh/yap/internal/check"
+	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/view/confirm"
+)
+
+// Built-in actions of the entries.
+const (
+	ActionBack   = "back"
+	ActionQuit   = "quit"
+	ActionLogout = "logout"
+)
+
+type Action struct {
+	Banner  string  `cfg:"banner"`
+	Entries []Entry `cfg:"entries"`
+	// Strict fails the target templates with missing keys.
+	Strict bool `cfg:"strict"`
+}
+
+type Entry struct {
+	Title       string `cfg:"title"`
+	Description string `cfg:"description"`
+	// Section groups the entries, sections are shown in the order of the first entry.
+	Section string `cfg:"section"`
+	// Target is the view ID to go, it can be a template of the session values.
+	Target string `cfg:"target"`
+	// Action is a built-in action; back, quit or logout.
+	Action string `cfg:"action"`
+	// Roles are required to see the entry, one of them is enough.
+	Roles []string `cfg:"roles"`
+	// Confirm shows a dialog with the rendered target before the selection.
+	Confirm *confirm.Confirm `cfg:"confirm"`
+}
+
+// Targets returns the view IDs of the entries, templates are skipped.
+func (a Action) Targets() []string {
+	var v []string
+
+	for _, e := range a.Entries {
+		if e.Target != "" && !model.IsTemplate(e.Target) {
+			v = append(v, e.Target)
+		}
+	}
+
+	return v
+}
+
+// Dynamic returns true if a target is a template, it can go to any view.
+func (a Action) Dynamic() bool {
+	for _, e := range a.Entries {
+		if model.IsTemplate(e.Target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Allowed returns the entries that the session can use, entries of the views
+// without a role of the session are hidden.
+func (a Action) Allowed(session *model.Session, index model.Index) Action {
+	entries := make([]Entry, 0, len(a.Entries))
+
+	for _, e := range a.Entries {
+		if !session.HasRole(e.Roles) {
+			continue
+		}
+
+		// templates are checked on the selection
+		if e.Target != "" && !model.IsTemplate(e.Target) && index != nil && !index.Allowed(e.Target) {
+			continue
+		}
+
+		entries = append(entries, e)
+	}
+
+	a.Entries = entries
+
+	return a
+}
+
+// Sections returns the entries grouped by the sections.
+func (a Action) Sections() ([]string, map[string][]Entry) {
+	var names []string
+
+	groups := make(map[string][]Entry)
+	for _, e := range a.Entries {
+		if _, ok := groups[e.Section]; !ok {
+			names = append(names, e.Section)
+		}
+
+		groups[e.Section] = append(groups[e.Section], e)
+	}
+
+	return names, groups
+}
+
+// Validate checks the entries.
+func (a *Action) Validate() error {
+	var issues check.Issues
+
+	if len(a.Entries) == 0 {
+		issues.Addf("entries", "at least one entry is required")
+	}
+
+	for i, e := range a.Entries {
+		path := check.Index("entries", i)
+
+		if e.Title == "" {
+			issues.Addf(check.Join(path, "title"), "title is required")
+		}
+
+		switch {
+		case e.Target == "" && e.Action == "":
+			issues.Addf(path, "target or action is required")
+		case e.Target != "" && e.Action != "":
+			issues.Addf(path, "only one of target and action is allowed")
+		}
+
+		issues.Add(check.Join(path, "target"), model.CheckTemplate(e.Target))
+
+		switch e.Action {
+		case "", ActionBack, ActionQuit, ActionLogout:
+		default:
+			issues.Addf(check.Join(path, "action"), "unknown action %q, use back, quit or logout", e.Action)
+		}
+
+		if e.Confirm != nil {
+			issues.Add(check.Join(path, "confirm"), e.Confirm.Validate())
+		}
+	}
+
+	return issues.Err()
+}
@@ -0,0 +1,50 @@
+package menu
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rytsh/yap/internal/tui/style"
+)
+
+// styles of the view, built from the theme.
+type styles struct {
+	section lipgloss.Style
+	info    lipgloss.Style
+	fail    lipgloss.Style
+	// base is the theme of the dialogs.
+	base *style.Styles
+}
+
+func newStyles(s *style.Styles) styles {
+	return styles{
+		base: s,
+
+		section: lipgloss.NewStyle().
+			Foreground(s.Highlight).
+			Bold(true).
+			PaddingLeft(1),
+
+		info: s.Blurred.Copy().PaddingLeft(2),
+		fail: s.Fail.Copy(),
+	}
+}
+
+// setModelStyles applies the theme to the list.
+func setModelStyles(l *list.Model, s *style.Styles) {
+	l.Styles.Title = l.Styles.Title.Copy().
+		Background(s.Highlight).
+		Foreground(lipgloss.Color("#FFFDF5"))
+	l.Styles.FilterPrompt = l.Styles.FilterPrompt.Copy().Foreground(s.Special)
+	l.Styles.FilterCursor = s.Focused.Copy()
+}
+
+// setDelegateStyles applies the theme to the entries.
+func setDelegateStyles(d *list.DefaultDelegate, s *style.Styles) {
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.Copy().
+		Foreground(s.Focused.GetForeground()).
+		BorderForeground(s.Highlight)
+	d.Styles.SelectedDesc = d.Styles.SelectedDesc.Copy().
+		Foreground(s.Blurred.GetForeground()).
+		BorderForeground(s.Highlight)
+	d.Styles.FilterMatch = d.Styles.FilterMatch.Copy().Foreground(s.Special)
+}
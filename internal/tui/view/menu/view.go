@@ -0,0 +1,343 @@
+package menu
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rytsh/yap/internal/audit"
+	"github.com/rytsh/yap/internal/tui/model"
+	"github.com/rytsh/yap/internal/tui/style"
+	"github.com/rytsh/yap/internal/tui/view/confirm"
+)
+
+type MenuModel struct {
+	width  int
+	height int
+	keymap keymapMenu
+	list   list.Model
+	styles styles
+
+	index   model.Index
+	session *model.Session
+
+	action Action
+	// visible is the action with the allowed entries of the session.
+	visible Action
+
+	// dialog is open until the pending entry is confirmed.
+	dialog        *confirm.Dialog
+	pending       Entry
+	pendingTarget string
+}
+
+type keymapMenu = struct {
+	selection, back, quit key.Binding
+}
+
+// item is an entry or a section header of the list.
+type item struct {
+	entry  Entry
+	header string
+}
+
+func (i item) Title() string       { return i.entry.Title }
+func (i item) Description() string { return i.entry.Description }
+
+// FilterValue of headers is empty, they are hidden while filtering.
+func (i item) FilterValue() string {
+	if i.header != "" {
+		return ""
+	}
+
+	return i.entry.Title + " " + i.entry.Description
+}
+
+// delegate renders the section headers and the entries.
+type delegate struct {
+	list.DefaultDelegate
+	section lipgloss.Style
+}
+
+func (d delegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	if i, ok := listItem.(item); ok && i.header != "" {
+		fmt.Fprint(w, "\n"+d.section.Render(i.header))
+
+		return
+	}
+
+	d.DefaultDelegate.Render(w, m, index, listItem)
+}
+
+func NewMenuModel(action Action) *MenuModel {
+	m := MenuModel{
+		action:  action,
+		visible: action,
+		keymap: keymapMenu{
+			selection: key.NewBinding(
+				key.WithKeys("enter"),
+				key.WithHelp("enter", "select"),
+			),
+			back: key.NewBinding(
+				key.WithKeys("esc"),
+				key.WithHelp("esc", "back"),
+			),
+			quit: key.NewBinding(
+				key.WithKeys("ctrl+c"),
+				key.WithHelp("ctrl+c", "quit"),
+			),
+		},
+	}
+
+	m.setStyles(style.DefaultStyles())
+
+	return &m
+}
+
+func (m *MenuModel) setStyles(s *style.Styles) {
+	m.styles = newStyles(s)
+
+	d := list.NewDefaultDelegate()
+	setDelegateStyles(&d, s)
+
+	var items []list.Item
+
+	names, groups := m.visible.Sections()
+	for _, name := range names {
+		if name != "" {
+			items = append(items, item{header: name})
+		}
+
+		for _, e := range groups[name] {
+			items = append(items, item{entry: e})
+		}
+	}
+
+	l := list.New(items, delegate{DefaultDelegate: d, section: m.styles.section}, m.width, m.listHeight())
+	l.Title = m.visible.Banner
+	l.SetShowTitle(m.visible.Banner != "")
+	l.SetStatusBarItemName("entry", "entries")
+	// item count includes the section headers
+	l.SetShowStatusBar(len(items) == len(m.visible.Entries))
+	l.DisableQuitKeybindings()
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{m.keymap.selection, m.keymap.back}
+	}
+
+	setModelStyles(&l, s)
+
+	m.list = l
+	m.skipHeader(-1)
+}
+
+func (m *MenuModel) SetIndex(index model.Index) {
+	m.index = index
+}
+
+func (m *MenuModel) Initialize(cfg model.Config) tea.Cmd {
+	m.width = cfg.Width
+	m.height = cfg.Height
+	// index sets the session of every view
+	m.session = cfg.Session
+	m.visible = m.action.Allowed(m.session, m.index)
+
+	s := cfg.Styles
+	if s == nil {
+		s = style.DefaultStyles()
+	}
+
+	// items are built again for the roles of the session
+	m.setStyles(s)
+
+	m.list.ResetFilter()
+	m.list.SetSize(m.width, m.listHeight())
+
+	return m.Init()
+}
+
+func (m *MenuModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *MenuModel) config() model.Config {
+	return model.Config{
+		Width:  m.width,
+		Height: m.height,
+	}
+}
+
+func (m *MenuModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if key.Matches(msg, m.keymap.quit) {
+			return m, tea.Quit
+		}
+
+		if m.dialog != nil {
+			return m.confirm(msg)
+		}
+
+		// keys belong to the filter input while typing
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+
+		switch {
+		case key.Matches(msg, m.keymap.selection):
+			return m.selectEntry()
+		case key.Matches(msg, m.keymap.back) && m.list.FilterState() == list.Unfiltered:
+			return m.index.PrevModel(m.config())
+		}
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.list.SetSize(m.width, m.listHeight())
+
+		return m, nil
+	}
+
+	if m.dialog != nil {
+		_, cmd := m.dialog.Update(msg)
+		return m, cmd
+	}
+
+	prev := m.list.Index()
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+
+	m.skipHeader(prev)
+
+	return m, cmd
+}
+
+func (m *MenuModel) selectEntry() (tea.Model, tea.Cmd) {
+	i, ok := m.list.SelectedItem().(item)
+	if !ok || i.header != "" {
+		return m, nil
+	}
+
+	target, err := model.Render(i.entry.Target, m.session.TemplateData(), m.action.Strict)
+	if err != nil {
+		m.session.Audit(audit.Event{Type: audit.TypeNavigate, Result: audit.ResultError, Error: err.Error()})
+
+		return m, m.list.NewStatusMessage(m.styles.fail.Render(err.Error()))
+	}
+
+	if i.entry.Confirm == nil {
+		return m.perform(i.entry, target)
+	}
+
+	params := []confirm.Param{{Name: "action", Value: i.entry.Action}}
+	if i.entry.Action == "" {
+		params = []confirm.Param{{Name: "target", Value: target}}
+	}
+
+	d, err := confirm.New(*i.entry.Confirm, m.session.TemplateData(), m.action.Strict, params, m.styles.base)
+	if err != nil {
+		return m, m.list.NewStatusMessage(m.styles.fail.Render(err.Error()))
+	}
+
+	m.dialog = d
+	m.pending = i.entry
+	m.pendingTarget = target
+
+	return m, d.Init()
+}
+
+// confirm sends the key to the dialog, the pending entry is selected if accepted.
+func (m *MenuModel) confirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	result, cmd := m.dialog.Update(msg)
+
+	switch result {
+	case confirm.Accepted:
+		m.dialog = nil
+		return m.perform(m.pending, m.pendingTarget)
+	case confirm.Canceled:
+		m.dialog = nil
+		m.session.Audit(audit.Event{
+			Type:   audit.TypeAction,
+			Action: "menu",
+			Name:   m.pending.Title,
+			Result: audit.ResultCanceled,
+		})
+	}
+
+	return m, cmd
+}
+
+// perform runs the built-in action or goes to the rendered target.
+func (m *MenuModel) perform(e Entry, target string) (tea.Model, tea.Cmd) {
+	if e.Action != "" {
+		m.session.Audit(audit.Event{Type: audit.TypeAction, Action: "menu", Name: e.Action})
+	}
+
+	switch e.Action {
+	case ActionBack:
+		return m.index.PrevModel(m.config())
+	case ActionQuit:
+		return m, tea.Quit
+	case ActionLogout:
+		m.session.Logout()
+
+		return m.index.InitModel(m.config())
+	}
+
+	next, cmd := m.index.Goto(target, m.config())
+	if next == tea.Model(m) && !m.index.Allowed(target) {
+		return m, m.list.NewStatusMessage(m.styles.fail.Render(fmt.Sprintf("unknown view %q", target)))
+	}
+
+	return next, cmd
+}
+
+// skipHeader moves the cursor from a section header to an entry, in the
+// direction of the last move.
+func (m *MenuModel) skipHeader(prev int) {
+	for range m.list.VisibleItems() {
+		i, ok := m.list.SelectedItem().(item)
+		if !ok || i.header == "" {
+			return
+		}
+
+		index := m.list.Index()
+		if index < prev {
+			m.list.CursorUp()
+
+			if m.list.Index() == index {
+				// header at the top, go down
+				prev = -1
+			}
+
+			continue
+		}
+
+		m.list.CursorDown()
+
+		if m.list.Index() == index {
+			return
+		}
+	}
+}
+
+func (m *MenuModel) listHeight() int {
+	return style.Max(5, m.height-2)
+}
+
+func (m *MenuModel) View() string {
+	info := ""
+	if identity := m.session.Identity(); identity != "" {
+		info = m.styles.info.Render("logged in as " + identity)
+	}
+
+	ui := lipgloss.JoinVertical(lipgloss.Left, m.list.View(), info)
+
+	if m.dialog != nil {
+		return m.dialog.View(style.Max(lipgloss.Width(ui), m.width), style.Max(lipgloss.Height(ui), m.height))
+	}
+
+	return ui
+}